@@ -24,7 +24,9 @@ import { Settings, TransportModal } from '../components/Modal';
 import { CreateLocation } from '../components/Modal/CreateLocation';
 import { notifications } from '@mantine/notifications';
 import { modals } from '@mantine/modals';
+import { Events } from '@wailsio/runtime';
 import {
+  LocationService,
   NodeService,
   UserService,
   LocalStorageService,
@@ -89,6 +91,30 @@ export function HomePage() {
     init();
   }, []);
 
+  // opened from the bookmarks of the system tray
+  useEffect(() => {
+    return Events.On('bookmarks:open', async (event: any) => {
+      const ctx: v1.FileContext = Array.isArray(event.data) ? event.data[0] : event.data;
+
+      try {
+        const target = (await LocationService.GetLocations()).find(
+          (l) => l?.node_id === ctx.node_id && l?.name === ctx.location,
+        );
+        if (!target) {
+          notifications.show({ color: 'red', message: `存储位置 ${ctx.location} 不存在` });
+          return;
+        }
+
+        await LocalStorageService.SetLocalStorage('selectedNodeId', ctx.node_id);
+        setNodeId(ctx.node_id!);
+        setLocation(target);
+        setPath((ctx.path ?? '').replace(/\/+$/, ''));
+      } catch (error: any) {
+        notifications.show({ color: 'red', message: error.message });
+      }
+    });
+  }, []);
+
   if (loading) {
     return (
       <Center py={300}>
@@ -180,9 +206,11 @@ export function HomePage() {
                 nodeList={nodeList}
                 key={refreshLocationKey}
                 selectedId={location?.id ?? ''}
-                onChangeLocation={(location) => {
-                  setPath('');
-                  setLocation(location);
+                onChangeLocation={(next) => {
+                  if (next?.id !== location?.id) {
+                    setPath('');
+                  }
+                  setLocation(next);
                 }}
               />
             </Box>
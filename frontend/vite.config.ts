@@ -1,8 +1,27 @@
-import { defineConfig } from 'vite';
+import { readFileSync } from 'node:fs';
+import { createRequire } from 'node:module';
+import { defineConfig, Plugin } from 'vite';
 import react from '@vitejs/plugin-react';
 import tsconfigPaths from 'vite-tsconfig-paths';
 
+const require = createRequire(import.meta.url);
+
+// bundles hls.js for the video player page served by the media service
+function playerScript(): Plugin {
+  return {
+    name: 'player-script',
+    apply: 'build',
+    generateBundle() {
+      this.emitFile({
+        type: 'asset',
+        fileName: 'player/hls.min.js',
+        source: readFileSync(require.resolve('hls.js/dist/hls.min.js')),
+      });
+    },
+  };
+}
+
 // https://vitejs.dev/config/
 export default defineConfig({
-  plugins: [react(), tsconfigPaths()],
+  plugins: [react(), tsconfigPaths(), playerScript()],
 });
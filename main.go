@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"io/fs"
 	"os"
 	"runtime"
 	"strings"
@@ -23,22 +24,49 @@ var darwinIcon []byte
 const appName = "PixelFS"
 
 func main() {
+	dist, err := fs.Sub(assets, "frontend/dist")
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load frontend assets")
+	}
+	services.SetMediaAssets(dist)
+
 	app := application.New(application.Options{
 		Name:        appName,
 		Description: "A cross-device file system, Transfer files based on s3-protocol.",
 		Services: []application.Service{
-			application.NewService(services.NewAuthService()),
+			// the other services use the database on startup
 			application.NewService(services.NewDatabaseService()),
+			application.NewService(services.NewActivityService()),
+			application.NewService(services.NewAudioService()),
+			application.NewService(services.NewAuthService()),
+			application.NewService(services.NewBackupService()),
+			application.NewService(services.NewBatchService()),
+			application.NewService(services.NewBookmarkService()),
+			application.NewService(services.NewDuplicateService()),
 			application.NewService(services.NewFileService()),
 			application.NewService(services.NewFileSyncService()),
+			application.NewService(services.NewInboxService()),
+			application.NewService(services.NewJournalService()),
 			application.NewService(services.NewLocalStorageService()),
 			application.NewService(services.NewLocationService()),
+			application.NewService(services.NewMediaService()),
+			application.NewService(services.NewMoveService()),
 			application.NewService(services.NewNodeService()),
 			application.NewService(services.NewPreferencesService()),
+			application.NewService(services.NewSearchService()),
 			application.NewService(services.NewStorageService()),
+			application.NewService(services.NewSyncHealthService()),
+			application.NewService(services.NewSyncMonitorService()),
+			application.NewService(services.NewSyncPauseService()),
+			application.NewService(services.NewSyncScheduleService()),
+			application.NewService(services.NewSyncTemplateService()),
 			application.NewService(services.NewSystemService()),
+			application.NewService(services.NewTopologyService()),
+			application.NewService(services.NewTrashService()),
+			application.NewService(services.NewUsageService()),
 			application.NewService(services.NewUserService()),
 			application.NewService(services.NewUtilService()),
+			application.NewService(services.NewWatchService()),
 		},
 		Assets: application.AssetOptions{
 			Handler: application.AssetFileServerFS(assets),
@@ -73,30 +101,118 @@ func main() {
 	})
 
 	systray := app.NewSystemTray()
-	menu := application.NewMenu()
-	menu.Add("打开应用").OnClick(func(ctx *application.Context) {
-		if runtime.GOOS == "darwin" {
-			window.UnMinimise()
-		} else {
-			window.Show()
-		}
-	})
-
-	menu.AddSeparator()
-	menu.Add("退出").OnClick(func(ctx *application.Context) { app.Quit() })
-
-	systray.SetMenu(menu)
+	systray.SetMenu(newSystrayMenu(app, window, false))
 	if runtime.GOOS == "windows" {
 		systray.OnClick(func() { window.Show() })
 	} else {
 		systray.SetIcon(darwinIcon)
 	}
 
+	// the database is only available once the services have started
+	app.OnApplicationEvent(events.Common.ApplicationStarted, func(*application.ApplicationEvent) {
+		systray.SetMenu(newSystrayMenu(app, window, true))
+	})
+	app.OnEvent(services.EventBookmarksChanged, func(*application.CustomEvent) {
+		systray.SetMenu(newSystrayMenu(app, window, true))
+	})
+	app.OnEvent(services.EventSyncPauseChanged, func(*application.CustomEvent) {
+		systray.SetMenu(newSystrayMenu(app, window, true))
+	})
+
 	if err := app.Run(); err != nil {
 		log.Fatal().Err(err)
 	}
 }
 
+func newSystrayMenu(app *application.App, window *application.WebviewWindow, started bool) *application.Menu {
+	menu := application.NewMenu()
+	menu.Add("打开应用").OnClick(func(ctx *application.Context) { showWindow(window) })
+
+	if started {
+		appendBookmarkMenu(menu.AddSubmenu("书签"), window)
+		appendSyncPauseMenu(menu)
+	}
+
+	menu.AddSeparator()
+	menu.Add("退出").OnClick(func(ctx *application.Context) { app.Quit() })
+
+	return menu
+}
+
+func appendBookmarkMenu(menu *application.Menu, window *application.WebviewWindow) {
+	bookmarkService := services.NewBookmarkService()
+
+	folders, err := bookmarkService.GetBookmarkFolders()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load bookmark folders")
+		return
+	}
+
+	bookmarks, err := bookmarkService.GetBookmarks()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to load bookmarks")
+		return
+	}
+
+	submenus := make(map[uint]*application.Menu)
+	for _, folder := range folders {
+		submenus[folder.ID] = menu.AddSubmenu(folder.Name)
+	}
+
+	for _, bookmark := range bookmarks {
+		parent := menu
+		if bookmark.FolderId != nil && submenus[*bookmark.FolderId] != nil {
+			parent = submenus[*bookmark.FolderId]
+		}
+
+		label := bookmark.Name
+		switch {
+		case bookmark.Offline:
+			label += "（离线）"
+		case bookmark.Broken:
+			label += "（已失效）"
+		}
+
+		id := bookmark.ID
+		parent.Add(label).OnClick(func(ctx *application.Context) {
+			showWindow(window)
+			if err := bookmarkService.OpenBookmark(id); err != nil {
+				log.Error().Err(err).Msg("failed to open bookmark")
+			}
+		})
+	}
+
+	if len(folders) == 0 && len(bookmarks) == 0 {
+		menu.Add("暂无书签").SetEnabled(false)
+	}
+}
+
+func appendSyncPauseMenu(menu *application.Menu) {
+	syncPauseService := services.NewSyncPauseService()
+
+	if syncPauseService.IsSyncPaused() {
+		menu.Add("恢复所有同步").OnClick(func(ctx *application.Context) {
+			if err := syncPauseService.ResumeAllSyncs(); err != nil {
+				log.Error().Err(err).Msg("failed to resume syncs")
+			}
+		})
+	} else {
+		menu.Add("暂停所有同步").OnClick(func(ctx *application.Context) {
+			if err := syncPauseService.PauseAllSyncs(); err != nil {
+				log.Error().Err(err).Msg("failed to pause syncs")
+			}
+		})
+	}
+}
+
+func showWindow(window *application.WebviewWindow) {
+	if runtime.GOOS == "darwin" {
+		window.UnMinimise()
+	} else {
+		window.Show()
+	}
+}
+
 func init() {
 	cfgFile := os.Getenv("PIXELFS_CONFIG")
 	if cfgFile != "" {
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"gorm.io/gorm"
+)
+
+const (
+	ActivityNavigate = "navigate"
+	ActivityPreview  = "preview"
+	ActivityPlay     = "play"
+	ActivityRename   = "rename"
+	ActivityDelete   = "delete"
+	ActivityMove     = "move"
+)
+
+const (
+	// repeated navigations to the same directory within this window are merged
+	activityNavigateMergeWindow = 10 * time.Minute
+
+	// older activities are pruned, every directory listing records one
+	activityMaxEntries = 5000
+)
+
+type ActivityService struct{}
+
+type Activity struct {
+	gorm.Model
+
+	Type     string `gorm:"index"`
+	NodeId   string `gorm:"index"`
+	Location string
+	Path     string
+
+	DestNodeId   *string
+	DestLocation *string
+	DestPath     *string
+}
+
+type ActivityFilter struct {
+	NodeId string
+	Types  []string
+	Limit  int
+	Offset int
+}
+
+var activity *ActivityService
+var onceActivity sync.Once
+
+func NewActivityService() *ActivityService {
+	if activity == nil {
+		onceActivity.Do(func() {
+			activity = &ActivityService{}
+		})
+	}
+
+	return activity
+}
+
+func (a *ActivityService) GetActivities(filter ActivityFilter) ([]*Activity, error) {
+	tx := activitiesOfNode(database.db.Model(&Activity{}), filter.NodeId)
+
+	if len(filter.Types) > 0 {
+		tx = tx.Where("type IN ?", filter.Types)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var activities []*Activity
+	if err := tx.Order("updated_at desc").Limit(limit).Offset(filter.Offset).Find(&activities).Error; err != nil {
+		return nil, err
+	}
+
+	return activities, nil
+}
+
+// GetRecentFiles returns the latest activity of each file that was previewed
+// or played, most recent first.
+func (a *ActivityService) GetRecentFiles(limit int) ([]*Activity, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	latest := database.db.Model(&Activity{}).
+		Select("MAX(id)").
+		Where("type IN ?", []string{ActivityPreview, ActivityPlay}).
+		Group("node_id, location, path")
+
+	var activities []*Activity
+	if err := database.db.Where("id IN (?)", latest).Order("updated_at desc").Limit(limit).Find(&activities).Error; err != nil {
+		return nil, err
+	}
+
+	return activities, nil
+}
+
+// RecordPreview is called by the frontend when a file is previewed.
+func (a *ActivityService) RecordPreview(ctx *pb.FileContext) error {
+	return a.record(ActivityPreview, ctx, nil)
+}
+
+func (a *ActivityService) RemoveActivity(id uint) error {
+	return database.db.Unscoped().Delete(&Activity{}, id).Error
+}
+
+// ClearActivities removes the activities that GetActivities lists for the
+// node, or all of them without a node.
+func (a *ActivityService) ClearActivities(nodeId string) error {
+	tx := database.db.Unscoped()
+	if nodeId == "" {
+		tx = tx.Where("1 = 1")
+	}
+
+	return activitiesOfNode(tx, nodeId).Delete(&Activity{}).Error
+}
+
+func (a *ActivityService) record(typ string, ctx *pb.FileContext, dest *pb.FileContext) error {
+	if typ == ActivityNavigate {
+		var last Activity
+		err := database.db.Where("type = ?", ActivityNavigate).Order("updated_at desc").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+
+		if last.ID != 0 && last.NodeId == ctx.NodeId && last.Location == ctx.Location && last.Path == ctx.Path &&
+			time.Since(last.UpdatedAt) < activityNavigateMergeWindow {
+			return database.db.Model(&last).Update("updated_at", time.Now()).Error
+		}
+	}
+
+	model := Activity{
+		Type:     typ,
+		NodeId:   ctx.NodeId,
+		Location: ctx.Location,
+		Path:     ctx.Path,
+	}
+
+	if dest != nil {
+		destNodeId, destLocation, destPath := dest.NodeId, dest.Location, dest.Path
+		model.DestNodeId = &destNodeId
+		model.DestLocation = &destLocation
+		model.DestPath = &destPath
+	}
+
+	if err := database.db.Create(&model).Error; err != nil {
+		return err
+	}
+
+	var ids []uint
+	if err := database.db.Model(&Activity{}).Order("id desc").Offset(activityMaxEntries).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return database.db.Unscoped().Delete(&Activity{}, ids).Error
+}
+
+// activitiesOfNode limits tx to the activities on the node, as src or dest.
+func activitiesOfNode(tx *gorm.DB, nodeId string) *gorm.DB {
+	if nodeId == "" {
+		return tx
+	}
+
+	return tx.Where("node_id = ? OR dest_node_id = ?", nodeId, nodeId)
+}
+
+// recordActivity never fails the operation that is being recorded.
+func recordActivity(typ string, ctx *pb.FileContext, dest *pb.FileContext) {
+	if err := activity.record(typ, ctx, dest); err != nil {
+		log.Warn().Err(err).Msgf("failed to record %s activity", typ)
+	}
+}
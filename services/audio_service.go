@@ -0,0 +1,472 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+type AudioService struct {
+	mu     sync.Mutex
+	tracks map[string]*AudioTrack
+}
+
+type AudioTrack struct {
+	Id        string
+	Name      string
+	Format    string
+	Size      int64
+	Duration  float64
+	StreamUrl string
+	Context   *pb.FileContext
+	Tags      *AudioTags
+
+	accessedAt time.Time
+}
+
+type AudioTags struct {
+	Title  string
+	Artist string
+	Album  string
+	Genre  string
+	Year   string
+	Track  string
+}
+
+const (
+	audioTagsProbeSize = 512 * 1024
+	audioTrackTTL      = 12 * time.Hour
+)
+
+var (
+	audioContentTypes = map[string]string{
+		".mp3":  "audio/mpeg",
+		".flac": "audio/flac",
+		".ogg":  "audio/ogg",
+		".oga":  "audio/ogg",
+		".opus": "audio/ogg",
+		".m4a":  "audio/mp4",
+	}
+
+	audio     *AudioService
+	onceAudio sync.Once
+)
+
+func NewAudioService() *AudioService {
+	if audio == nil {
+		onceAudio.Do(func() {
+			audio = &AudioService{
+				tracks: make(map[string]*AudioTrack),
+			}
+
+			NewMediaService().mux.HandleFunc("GET /audio/{id}", audio.handleStream)
+		})
+	}
+
+	return audio
+}
+
+func (a *AudioService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.pruneTracks()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// GetAudioTrack returns the stream url and metadata of a remote audio file,
+// tags are parsed from the first blocks of the file.
+func (a *AudioService) GetAudioTrack(ctx *pb.FileContext) (*AudioTrack, error) {
+	stat, err := file.StatFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	track, err := a.registerTrack(ctx, stat)
+	if err != nil {
+		return nil, err
+	}
+
+	if track.Tags, err = a.readTags(ctx, track.Format); err != nil {
+		return nil, err
+	}
+
+	recordActivity(ActivityPlay, ctx, nil)
+	return track, nil
+}
+
+// GetAudioPlaylist builds a playlist from the audio files of a remote directory,
+// ordered by file name. Tags are not read here, use GetAudioTrack per track.
+func (a *AudioService) GetAudioPlaylist(ctx *pb.FileContext) ([]*AudioTrack, error) {
+	files, err := file.listFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return strings.ToLower(files[i].Name) < strings.ToLower(files[j].Name)
+	})
+
+	var playlist []*AudioTrack
+	for _, fileInfo := range files {
+		if fileInfo.Type == pb.FileType_DIR || !isAudioFile(fileInfo.Name) {
+			continue
+		}
+
+		track, err := a.registerTrack(&pb.FileContext{
+			NodeId:   ctx.NodeId,
+			Location: ctx.Location,
+			Path:     path.Join(ctx.Path, fileInfo.Name),
+		}, fileInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		playlist = append(playlist, track)
+	}
+
+	return playlist, nil
+}
+
+func (a *AudioService) registerTrack(ctx *pb.FileContext, fileInfo *pb.File) (*AudioTrack, error) {
+	id, err := randomId()
+	if err != nil {
+		return nil, err
+	}
+
+	_, name := path.Split(ctx.Path)
+	track := &AudioTrack{
+		Id:        id,
+		Name:      name,
+		Format:    strings.TrimPrefix(strings.ToLower(path.Ext(name)), "."),
+		Size:      fileInfo.Size,
+		Duration:  float64(fileInfo.Duration),
+		StreamUrl: fmt.Sprintf("%s/audio/%s", media.baseUrl(), id),
+		Context:   ctx,
+	}
+
+	track.accessedAt = time.Now()
+	a.mu.Lock()
+	a.tracks[id] = track
+	a.mu.Unlock()
+
+	return track, nil
+}
+
+func (a *AudioService) handleStream(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	track, ok := a.tracks[r.PathValue("id")]
+	if ok {
+		track.accessedAt = time.Now()
+	}
+	a.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	reader, err := file.openFile(track.Context)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	var modTime time.Time
+	if reader.file.ModifiedAt != nil {
+		modTime = reader.file.ModifiedAt.AsTime()
+	}
+
+	if contentType, ok := audioContentTypes["."+track.Format]; ok {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	http.ServeContent(w, r, track.Name, modTime, reader)
+}
+
+// pruneTracks forgets the tracks that weren't streamed for a while.
+func (a *AudioService) pruneTracks() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for id, track := range a.tracks {
+		if time.Since(track.accessedAt) > audioTrackTTL {
+			delete(a.tracks, id)
+		}
+	}
+}
+
+func (a *AudioService) readTags(ctx *pb.FileContext, format string) (*AudioTags, error) {
+	reader, err := file.openFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(reader, audioTagsProbeSize))
+	if err != nil {
+		return nil, err
+	}
+
+	tags := &AudioTags{}
+	switch {
+	case bytes.HasPrefix(data, []byte("ID3")):
+		parseID3v2(data, tags)
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		parseFlacTags(data, tags)
+	case bytes.HasPrefix(data, []byte("OggS")):
+		parseOggTags(data, tags)
+	case format == "m4a":
+		parseMp4Tags(data, tags)
+	}
+
+	return tags, nil
+}
+
+func isAudioFile(name string) bool {
+	_, ok := audioContentTypes[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+func (t *AudioTags) set(key, value string) {
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	if value == "" {
+		return
+	}
+
+	switch strings.ToUpper(key) {
+	case "TIT2", "TT2", "TITLE", "©NAM":
+		t.Title = value
+	case "TPE1", "TP1", "ARTIST", "©ART":
+		t.Artist = value
+	case "TALB", "TAL", "ALBUM", "©ALB":
+		t.Album = value
+	case "TCON", "TCO", "GENRE", "©GEN":
+		t.Genre = value
+	case "TYER", "TYE", "TDRC", "DATE", "YEAR", "©DAY":
+		t.Year = value
+	case "TRCK", "TRK", "TRACKNUMBER":
+		t.Track = value
+	}
+}
+
+func parseID3v2(data []byte, tags *AudioTags) {
+	if len(data) < 10 {
+		return
+	}
+
+	version := data[3]
+	end := 10 + int(syncsafeInt(data[6:10]))
+	if end > len(data) {
+		end = len(data)
+	}
+
+	headerSize := 10
+	if version == 2 {
+		headerSize = 6
+	}
+
+	for pos := 10; pos+headerSize <= end; {
+		var id string
+		var size int
+
+		switch version {
+		case 2:
+			id = string(data[pos : pos+3])
+			size = int(data[pos+3])<<16 | int(data[pos+4])<<8 | int(data[pos+5])
+		case 3:
+			id = string(data[pos : pos+4])
+			size = int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		default:
+			id = string(data[pos : pos+4])
+			size = int(syncsafeInt(data[pos+4 : pos+8]))
+		}
+
+		if id[0] == 0 || size <= 0 || pos+headerSize+size > end {
+			return
+		}
+
+		frame := data[pos+headerSize : pos+headerSize+size]
+		if id[0] == 'T' {
+			tags.set(id, decodeID3Text(frame))
+		}
+
+		pos += headerSize + size
+	}
+}
+
+func syncsafeInt(b []byte) uint32 {
+	return uint32(b[0]&0x7f)<<21 | uint32(b[1]&0x7f)<<14 | uint32(b[2]&0x7f)<<7 | uint32(b[3]&0x7f)
+}
+
+func decodeID3Text(frame []byte) string {
+	if len(frame) < 2 {
+		return ""
+	}
+
+	switch frame[0] {
+	case 1, 2:
+		return decodeUTF16(frame[1:], frame[0] == 2)
+	case 3:
+		return string(frame[1:])
+	default:
+		return decodeLatin1(string(frame[1:]))
+	}
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	if len(b) >= 2 {
+		switch {
+		case b[0] == 0xff && b[1] == 0xfe:
+			b, bigEndian = b[2:], false
+		case b[0] == 0xfe && b[1] == 0xff:
+			b, bigEndian = b[2:], true
+		}
+	}
+
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		if bigEndian {
+			units = append(units, binary.BigEndian.Uint16(b[i:]))
+		} else {
+			units = append(units, binary.LittleEndian.Uint16(b[i:]))
+		}
+	}
+
+	return string(utf16.Decode(units))
+}
+
+func parseFlacTags(data []byte, tags *AudioTags) {
+	for pos := 4; pos+4 <= len(data); {
+		header := data[pos]
+		size := int(data[pos+1])<<16 | int(data[pos+2])<<8 | int(data[pos+3])
+		pos += 4
+
+		if pos+size > len(data) {
+			return
+		}
+
+		// block type 4 is VORBIS_COMMENT
+		if header&0x7f == 4 {
+			parseVorbisComment(data[pos:pos+size], tags)
+			return
+		}
+
+		if header&0x80 != 0 {
+			return
+		}
+
+		pos += size
+	}
+}
+
+func parseOggTags(data []byte, tags *AudioTags) {
+	for _, marker := range [][]byte{[]byte("\x03vorbis"), []byte("OpusTags")} {
+		if index := bytes.Index(data, marker); index >= 0 {
+			parseVorbisComment(data[index+len(marker):], tags)
+			return
+		}
+	}
+}
+
+func parseVorbisComment(data []byte, tags *AudioTags) {
+	readString := func(pos int) (string, int, bool) {
+		if pos+4 > len(data) {
+			return "", pos, false
+		}
+
+		size := int(binary.LittleEndian.Uint32(data[pos:]))
+		pos += 4
+		if size < 0 || pos+size > len(data) {
+			return "", pos, false
+		}
+
+		return string(data[pos : pos+size]), pos + size, true
+	}
+
+	_, pos, ok := readString(0) // vendor
+	if !ok || pos+4 > len(data) {
+		return
+	}
+
+	count := int(binary.LittleEndian.Uint32(data[pos:]))
+	pos += 4
+
+	for i := 0; i < count; i++ {
+		var comment string
+		if comment, pos, ok = readString(pos); !ok {
+			return
+		}
+
+		if key, value, found := strings.Cut(comment, "="); found {
+			tags.set(key, value)
+		}
+	}
+}
+
+// parseMp4Tags walks moov/udta/meta/ilst, it only finds tags when the moov
+// atom is placed at the beginning of the file.
+func parseMp4Tags(data []byte, tags *AudioTags) {
+	var walk func(data []byte, containers []string)
+	walk = func(data []byte, containers []string) {
+		for pos := 0; pos+8 <= len(data); {
+			size := int(binary.BigEndian.Uint32(data[pos:]))
+			name := string(data[pos+4 : pos+8])
+			if size < 8 || pos+size > len(data) {
+				return
+			}
+
+			body := data[pos+8 : pos+size]
+			switch {
+			case len(containers) > 0 && name == containers[0]:
+				if name == "meta" && len(body) >= 4 {
+					body = body[4:]
+				}
+
+				walk(body, containers[1:])
+			case len(containers) == 0 && len(body) >= 16 && string(body[4:8]) == "data":
+				value := body[16:]
+				if name == "trkn" && len(value) >= 4 {
+					tags.set("TRACKNUMBER", strconv.Itoa(int(binary.BigEndian.Uint16(value[2:4]))))
+				} else {
+					tags.set(strings.ToUpper(decodeLatin1(name)), string(value))
+				}
+			}
+
+			pos += size
+		}
+	}
+
+	walk(data, []string{"moov", "udta", "meta", "ilst"})
+}
+
+func decodeLatin1(s string) string {
+	runes := make([]rune, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		runes = append(runes, rune(s[i]))
+	}
+
+	return string(runes)
+}
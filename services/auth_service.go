@@ -37,6 +37,13 @@ func (a *AuthService) GetUserToken() (string, error) {
 	return cfg.Token, nil
 }
 
+// loggedIn reports whether there is a token, background jobs that call the
+// server are skipped without one.
+func (a *AuthService) loggedIn() bool {
+	token, err := a.GetUserToken()
+	return err == nil && token != ""
+}
+
 func (a *AuthService) Logout() error {
 	return config.Remove("token")
 }
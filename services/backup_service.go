@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/pixelfs/pixelfs/util"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	EventBackupRunFinished = "backup:finished"
+
+	backupDefaultKeepRuns = 10
+)
+
+// BackupService runs one-way backups from a local directory to a remote
+// directory. Only files that changed since the last run are uploaded.
+type BackupService struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	running map[uint]context.CancelFunc
+}
+
+type BackupJob struct {
+	gorm.Model
+
+	Name      string
+	LocalPath string
+	NodeId    string
+	Location  string
+	Path      string
+	Include   []string `gorm:"serializer:json"` // empty includes every file
+	Exclude   []string `gorm:"serializer:json"`
+	Interval  int      // seconds between scheduled runs, 0 only runs manually
+	HashCheck bool     // compare hashes even if size and mtime are unchanged
+	KeepRuns  int
+	Enabled   bool
+	LastRunAt *time.Time
+}
+
+type BackupRun struct {
+	gorm.Model
+
+	JobId        uint   `gorm:"index"`
+	Status       string // running, success, failed, cancelled
+	Scanned      int
+	Uploaded     int
+	Skipped      int
+	Failed       int
+	UploadedSize int64
+	Errors       []string `gorm:"serializer:json"`
+	StartedAt    time.Time
+	FinishedAt   *time.Time
+}
+
+// BackupFileState is the last uploaded version of a local file.
+type BackupFileState struct {
+	ID         uint   `gorm:"primarykey"`
+	JobId      uint   `gorm:"uniqueIndex:idx_backup_file_state"`
+	RelPath    string `gorm:"uniqueIndex:idx_backup_file_state"`
+	Size       int64
+	ModifiedAt time.Time
+	Hash       string
+	UploadedAt time.Time
+}
+
+var backup *BackupService
+var onceBackup sync.Once
+
+func NewBackupService() *BackupService {
+	if backup == nil {
+		onceBackup.Do(func() {
+			backup = &BackupService{running: make(map[uint]context.CancelFunc)}
+		})
+	}
+
+	return backup
+}
+
+func (b *BackupService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	b.ctx = ctx
+
+	// runs that were in progress when the application quit
+	if err := database.db.Model(&BackupRun{}).Where("status = ?", "running").
+		Updates(map[string]any{"status": "failed", "finished_at": time.Now()}).Error; err != nil {
+		log.Error().Err(err).Msg("failed to mark interrupted backup runs")
+	}
+
+	go b.schedule(ctx)
+	return nil
+}
+
+func (b *BackupService) schedule(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.runScheduled(); err != nil {
+				log.Error().Err(err).Msg("failed to run scheduled backups")
+			}
+		}
+	}
+}
+
+func (b *BackupService) GetBackupJobs() ([]*BackupJob, error) {
+	var jobs []*BackupJob
+	if err := database.db.Order("id").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+func (b *BackupService) CreateBackupJob(job *BackupJob) (*BackupJob, error) {
+	if err := b.validate(job); err != nil {
+		return nil, err
+	}
+
+	job.ID = 0
+	job.LastRunAt = nil
+	if err := database.db.Create(job).Error; err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (b *BackupService) UpdateBackupJob(job *BackupJob) (*BackupJob, error) {
+	if err := b.validate(job); err != nil {
+		return nil, err
+	}
+
+	var current BackupJob
+	if err := database.db.First(&current, job.ID).Error; err != nil {
+		return nil, err
+	}
+
+	// a different source or destination makes the file states meaningless
+	if current.LocalPath != job.LocalPath || current.NodeId != job.NodeId ||
+		current.Location != job.Location || current.Path != job.Path {
+		if err := database.db.Where("job_id = ?", job.ID).Delete(&BackupFileState{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	job.CreatedAt = current.CreatedAt
+	job.LastRunAt = current.LastRunAt
+	if err := database.db.Save(job).Error; err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (b *BackupService) RemoveBackupJob(id uint) error {
+	b.CancelBackupJob(id)
+
+	return database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("job_id = ?", id).Delete(&BackupFileState{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("job_id = ?", id).Delete(&BackupRun{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&BackupJob{}, id).Error
+	})
+}
+
+func (b *BackupService) GetBackupRuns(jobId uint) ([]*BackupRun, error) {
+	var runs []*BackupRun
+	if err := database.db.Where("job_id = ?", jobId).Order("id desc").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// RunBackupJob starts a run in the background, the report is sent with the
+// backup:finished event when it is done.
+func (b *BackupService) RunBackupJob(id uint) (*BackupRun, error) {
+	var job BackupJob
+	if err := database.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+
+	return b.start(&job)
+}
+
+func (b *BackupService) CancelBackupJob(id uint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if cancel, ok := b.running[id]; ok {
+		cancel()
+	}
+}
+
+func (b *BackupService) validate(job *BackupJob) error {
+	if job.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if job.NodeId == "" || job.Location == "" {
+		return errors.New("remote directory is required")
+	}
+
+	stat, err := os.Stat(job.LocalPath)
+	if err != nil {
+		return err
+	}
+
+	if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", job.LocalPath)
+	}
+
+	if job.Interval < 0 || job.KeepRuns < 0 {
+		return errors.New("interval and kept runs must not be negative")
+	}
+
+	return nil
+}
+
+func (b *BackupService) runScheduled() error {
+	var jobs []*BackupJob
+	if err := database.db.Where("enabled = ? AND interval > 0", true).Find(&jobs).Error; err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if job.LastRunAt != nil && time.Since(*job.LastRunAt) < time.Duration(job.Interval)*time.Second {
+			continue
+		}
+
+		b.mu.Lock()
+		_, running := b.running[job.ID]
+		b.mu.Unlock()
+
+		if !running {
+			if _, err := b.start(job); err != nil {
+				log.Error().Err(err).Msgf("failed to start backup %s", job.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *BackupService) start(job *BackupJob) (*BackupRun, error) {
+	b.mu.Lock()
+	if _, ok := b.running[job.ID]; ok {
+		b.mu.Unlock()
+		return nil, errors.New("backup is already running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b.running[job.ID] = cancel
+	b.mu.Unlock()
+
+	now := time.Now()
+	run := BackupRun{JobId: job.ID, Status: "running", StartedAt: now}
+	err := database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&run).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(job).Update("last_run_at", now).Error
+	})
+	if err != nil {
+		b.finish(job.ID)
+		return nil, err
+	}
+
+	// the worker keeps updating its run
+	result := run
+
+	go func() {
+		defer b.finish(job.ID)
+
+		b.run(ctx, job, &run)
+
+		switch {
+		case ctx.Err() != nil:
+			run.Status = "cancelled"
+		case run.Failed > 0:
+			run.Status = "failed"
+		default:
+			run.Status = "success"
+		}
+
+		finishedAt := time.Now()
+		run.FinishedAt = &finishedAt
+		if err := database.db.Save(&run).Error; err != nil {
+			log.Error().Err(err).Msg("failed to save backup run")
+		}
+
+		if err := b.prune(job); err != nil {
+			log.Error().Err(err).Msg("failed to prune backup runs")
+		}
+
+		application.Get().EmitEvent(EventBackupRunFinished, &run)
+	}()
+
+	return &result, nil
+}
+
+func (b *BackupService) finish(id uint) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if cancel, ok := b.running[id]; ok {
+		cancel()
+		delete(b.running, id)
+	}
+}
+
+func (b *BackupService) run(ctx context.Context, job *BackupJob, run *BackupRun) {
+	var states []*BackupFileState
+	if err := database.db.Where("job_id = ?", job.ID).Find(&states).Error; err != nil {
+		run.Failed++
+		run.Errors = append(run.Errors, err.Error())
+		return
+	}
+
+	known := make(map[string]*BackupFileState, len(states))
+	for _, state := range states {
+		known[state.RelPath] = state
+	}
+
+	err := filepath.WalkDir(job.LocalPath, func(localPath string, entry fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			run.Failed++
+			run.Errors = append(run.Errors, err.Error())
+			return nil
+		}
+
+		rel, err := filepath.Rel(job.LocalPath, localPath)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if matchAnyGlob(job.Exclude, rel) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !entry.Type().IsRegular() || matchAnyGlob(job.Exclude, rel) ||
+			(len(job.Include) > 0 && !matchAnyGlob(job.Include, rel)) {
+			return nil
+		}
+
+		run.Scanned++
+		uploaded, size, err := b.backupFile(job, localPath, rel, known[rel])
+		switch {
+		case err != nil:
+			run.Failed++
+			run.Errors = append(run.Errors, fmt.Sprintf("%s: %s", rel, err))
+		case uploaded:
+			run.Uploaded++
+			run.UploadedSize += size
+		default:
+			run.Skipped++
+		}
+
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		run.Failed++
+		run.Errors = append(run.Errors, err.Error())
+	}
+}
+
+// backupFile uploads the file if it changed since the state of the last upload.
+func (b *BackupService) backupFile(job *BackupJob, localPath, rel string, state *BackupFileState) (bool, int64, error) {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return false, 0, err
+	}
+
+	unchanged := state != nil && state.Size == info.Size() && state.ModifiedAt.Equal(info.ModTime())
+	if unchanged && !job.HashCheck {
+		return false, 0, nil
+	}
+
+	hash, err := util.GetFileHash(localPath)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if state != nil && state.Size == info.Size() && state.Hash == hash {
+		// only touched, remember the new mtime
+		if !unchanged {
+			return false, 0, database.db.Model(state).Update("modified_at", info.ModTime()).Error
+		}
+
+		return false, 0, nil
+	}
+
+	dest := &pb.FileContext{NodeId: job.NodeId, Location: job.Location, Path: path.Join(job.Path, rel)}
+	if err := file.mkdirAll(&pb.FileContext{NodeId: dest.NodeId, Location: dest.Location, Path: path.Dir(dest.Path)}); err != nil {
+		return false, 0, err
+	}
+
+	if err := file.uploadLocalFile(dest, localPath); err != nil {
+		return false, 0, err
+	}
+
+	err = database.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "job_id"}, {Name: "rel_path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size", "modified_at", "hash", "uploaded_at"}),
+	}).Create(&BackupFileState{
+		JobId:      job.ID,
+		RelPath:    rel,
+		Size:       info.Size(),
+		ModifiedAt: info.ModTime(),
+		Hash:       hash,
+		UploadedAt: time.Now(),
+	}).Error
+
+	return true, info.Size(), err
+}
+
+// prune keeps the latest KeepRuns runs of the job.
+func (b *BackupService) prune(job *BackupJob) error {
+	keep := job.KeepRuns
+	if keep <= 0 {
+		keep = backupDefaultKeepRuns
+	}
+
+	var ids []uint
+	if err := database.db.Model(&BackupRun{}).Where("job_id = ?", job.ID).
+		Order("id desc").Offset(keep).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return database.db.Unscoped().Delete(&BackupRun{}, ids).Error
+}
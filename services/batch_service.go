@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+const (
+	EventBatchJobProgress = "batch:progress"
+	EventBatchJobFinished = "batch:finished"
+
+	batchJobConcurrency = 3
+)
+
+// BatchService keeps track of the batch file operations started in this session.
+type BatchService struct {
+	mu   sync.Mutex
+	jobs []*BatchJob
+}
+
+type BatchJob struct {
+	Id         string
+	Type       string // remove, move, copy, download
+	Status     string // running, success, failed, cancelled
+	Total      int
+	Completed  int
+	Failed     int
+	Cancelled  int
+	Progress   int
+	Items      []*BatchJobItem
+	StartedAt  time.Time
+	FinishedAt *time.Time
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+type BatchJobItem struct {
+	Src       *pb.FileContext
+	Dest      *pb.FileContext
+	LocalPath string
+	Status    string // pending, running, success, failed, cancelled
+	Error     string
+}
+
+var batch *BatchService
+var onceBatch sync.Once
+
+func NewBatchService() *BatchService {
+	if batch == nil {
+		onceBatch.Do(func() {
+			batch = &BatchService{}
+		})
+	}
+
+	return batch
+}
+
+func (b *BatchService) GetBatchJobs() []*BatchJob {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	jobs := make([]*BatchJob, 0, len(b.jobs))
+	for i := len(b.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, b.jobs[i].snapshot())
+	}
+
+	return jobs
+}
+
+func (b *BatchService) GetBatchJob(id string) (*BatchJob, error) {
+	job, err := b.findJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return job.snapshot(), nil
+}
+
+// CancelBatchJob stops scheduling the remaining items, items that are already
+// running are finished.
+func (b *BatchService) CancelBatchJob(id string) error {
+	job, err := b.findJob(id)
+	if err != nil {
+		return err
+	}
+
+	job.cancel()
+	return nil
+}
+
+// ClearBatchJobs forgets about the finished jobs.
+func (b *BatchService) ClearBatchJobs() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var running []*BatchJob
+	for _, job := range b.jobs {
+		job.mu.Lock()
+		if job.FinishedAt == nil {
+			running = append(running, job)
+		}
+		job.mu.Unlock()
+	}
+
+	b.jobs = running
+}
+
+func (b *BatchService) findJob(id string) (*BatchJob, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, job := range b.jobs {
+		if job.Id == id {
+			return job, nil
+		}
+	}
+
+	return nil, fmt.Errorf("batch job %s not found", id)
+}
+
+// startJob runs fn for every item with bounded concurrency in the background.
+func (b *BatchService) startJob(typ string, items []*BatchJobItem, fn func(item *BatchJobItem) error) (*BatchJob, error) {
+	if len(items) == 0 {
+		return nil, errors.New("no files selected")
+	}
+
+	id, err := randomId()
+	if err != nil {
+		return nil, err
+	}
+
+	jobCtx, cancel := context.WithCancel(context.Background())
+	job := &BatchJob{
+		Id:        id,
+		Type:      typ,
+		Status:    "running",
+		Total:     len(items),
+		Items:     items,
+		StartedAt: time.Now(),
+		cancel:    cancel,
+	}
+
+	for _, item := range items {
+		item.Status = "pending"
+	}
+
+	b.mu.Lock()
+	b.jobs = append(b.jobs, job)
+	b.mu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		var wg sync.WaitGroup
+		queue := make(chan *BatchJobItem)
+
+		for i := 0; i < batchJobConcurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for item := range queue {
+					job.setItemStatus(item, "running", nil)
+					job.setItemStatus(item, "success", fn(item))
+				}
+			}()
+		}
+
+	schedule:
+		for _, item := range items {
+			select {
+			case <-jobCtx.Done():
+				break schedule
+			case queue <- item:
+			}
+		}
+		close(queue)
+		wg.Wait()
+
+		job.finish()
+		application.Get().EmitEvent(EventBatchJobFinished, job.snapshot())
+	}()
+
+	return job.snapshot(), nil
+}
+
+func (j *BatchJob) setItemStatus(item *BatchJobItem, status string, err error) {
+	j.mu.Lock()
+	if err != nil {
+		item.Status = "failed"
+		item.Error = err.Error()
+		j.Failed++
+	} else {
+		item.Status = status
+		if status == "success" {
+			j.Completed++
+		}
+	}
+
+	j.Progress = int(float64(j.Completed+j.Failed) / float64(j.Total) * 100)
+	j.mu.Unlock()
+
+	application.Get().EmitEvent(EventBatchJobProgress, j.snapshot())
+}
+
+func (j *BatchJob) finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, item := range j.Items {
+		if item.Status == "pending" {
+			item.Status = "cancelled"
+			j.Cancelled++
+		}
+	}
+
+	switch {
+	case j.Cancelled > 0:
+		j.Status = "cancelled"
+	case j.Failed > 0:
+		j.Status = "failed"
+	default:
+		j.Status = "success"
+	}
+
+	now := time.Now()
+	j.FinishedAt = &now
+}
+
+func (j *BatchJob) snapshot() *BatchJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	copied := &BatchJob{
+		Id:         j.Id,
+		Type:       j.Type,
+		Status:     j.Status,
+		Total:      j.Total,
+		Completed:  j.Completed,
+		Failed:     j.Failed,
+		Cancelled:  j.Cancelled,
+		Progress:   j.Progress,
+		Items:      make([]*BatchJobItem, 0, len(j.Items)),
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+	}
+
+	for _, item := range j.Items {
+		itemCopy := *item
+		copied.Items = append(copied.Items, &itemCopy)
+	}
+
+	return copied
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+func TestBatchJobStatus(t *testing.T) {
+	application.New(application.Options{})
+
+	tests := []struct {
+		name          string
+		fail          []bool
+		wantStatus    string
+		wantCompleted int
+		wantFailed    int
+	}{
+		{"all succeed", []bool{false, false, false, false}, "success", 4, 0},
+		{"one fails", []bool{false, true, false, false}, "failed", 3, 1},
+		{"all fail", []bool{true, true}, "failed", 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &BatchService{}
+
+			var items []*BatchJobItem
+			for range tt.fail {
+				items = append(items, &BatchJobItem{})
+			}
+
+			job, err := b.startJob("copy", items, func(item *BatchJobItem) error {
+				for i, it := range items {
+					if it == item && tt.fail[i] {
+						return errors.New("failed")
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := waitBatchJob(t, b, job.Id)
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", got.Status, tt.wantStatus)
+			}
+
+			if got.Completed != tt.wantCompleted || got.Failed != tt.wantFailed || got.Cancelled != 0 {
+				t.Errorf("Completed, Failed, Cancelled = %d, %d, %d, want %d, %d, 0",
+					got.Completed, got.Failed, got.Cancelled, tt.wantCompleted, tt.wantFailed)
+			}
+
+			if got.Progress != 100 {
+				t.Errorf("Progress = %d, want 100", got.Progress)
+			}
+
+			for i, item := range got.Items {
+				want := "success"
+				if tt.fail[i] {
+					want = "failed"
+				}
+
+				if item.Status != want {
+					t.Errorf("item %d status = %s, want %s", i, item.Status, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchJobProgress(t *testing.T) {
+	application.New(application.Options{})
+
+	b := &BatchService{}
+	items := make([]*BatchJobItem, 4)
+	for i := range items {
+		items[i] = &BatchJobItem{}
+	}
+
+	results := make(chan error)
+	job, err := b.startJob("remove", items, func(*BatchJobItem) error {
+		return <-results
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Status != "running" || job.Progress != 0 {
+		t.Errorf("started job = %s, %d%%, want running, 0%%", job.Status, job.Progress)
+	}
+
+	// failed items count as done
+	want := []int{25, 50, 75, 100}
+	for i := range items {
+		var result error
+		if i%2 == 1 {
+			result = errors.New("failed")
+		}
+		results <- result
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			got, err := b.GetBatchJob(job.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Completed+got.Failed == i+1 {
+				if got.Progress != want[i] {
+					t.Errorf("Progress after %d items = %d, want %d", i+1, got.Progress, want[i])
+				}
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatalf("item %d did not finish", i)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	got := waitBatchJob(t, b, job.Id)
+	if got.Status != "failed" || got.Completed != 2 || got.Failed != 2 {
+		t.Errorf("finished job = %s, %d completed, %d failed, want failed, 2, 2", got.Status, got.Completed, got.Failed)
+	}
+}
+
+func TestBatchJobCancel(t *testing.T) {
+	application.New(application.Options{})
+
+	b := &BatchService{}
+	items := make([]*BatchJobItem, 50)
+	for i := range items {
+		items[i] = &BatchJobItem{}
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	job, err := b.startJob("move", items, func(*BatchJobItem) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range batchJobConcurrency {
+		<-started
+	}
+
+	if err := b.CancelBatchJob(job.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	// items that were scheduled before the cancel still finish
+	close(release)
+	go func() {
+		for range started {
+		}
+	}()
+
+	got := waitBatchJob(t, b, job.Id)
+	close(started)
+	if got.Status != "cancelled" {
+		t.Errorf("Status = %s, want cancelled", got.Status)
+	}
+
+	if got.Completed < batchJobConcurrency || got.Cancelled == 0 || got.Completed+got.Cancelled != len(items) {
+		t.Errorf("Completed, Cancelled = %d, %d of %d items", got.Completed, got.Cancelled, len(items))
+	}
+
+	for i, item := range got.Items {
+		if item.Status != "success" && item.Status != "cancelled" {
+			t.Errorf("item %d status = %s", i, item.Status)
+		}
+	}
+
+	if err := b.CancelBatchJob("missing"); err == nil {
+		t.Error("CancelBatchJob() of an unknown job succeeded")
+	}
+}
+
+func waitBatchJob(t *testing.T, b *BatchService, id string) *BatchJob {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := b.GetBatchJob(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if job.FinishedAt != nil {
+			return job
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("batch job %s did not finish", id)
+	return nil
+}
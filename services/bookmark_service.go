@@ -0,0 +1,343 @@
+package services
+
+import (
+	"errors"
+	"path"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+)
+
+const (
+	EventBookmarksChanged = "bookmarks:changed"
+	EventBookmarkOpen     = "bookmarks:open"
+)
+
+type BookmarkService struct{}
+
+type BookmarkFolder struct {
+	gorm.Model
+
+	Name string
+	Sort int
+}
+
+type Bookmark struct {
+	gorm.Model
+
+	Name     string
+	FolderId *uint
+	Sort     int
+	NodeId   string
+	Location string
+	Path     string
+
+	Broken    bool // the target is gone
+	Offline   bool // the node is offline, the target could not be checked
+	CheckedAt *time.Time
+}
+
+var bookmark *BookmarkService
+var onceBookmark sync.Once
+
+func NewBookmarkService() *BookmarkService {
+	if bookmark == nil {
+		onceBookmark.Do(func() {
+			bookmark = &BookmarkService{}
+		})
+	}
+
+	return bookmark
+}
+
+func (b *Bookmark) fileContext() *pb.FileContext {
+	return &pb.FileContext{
+		NodeId:   b.NodeId,
+		Location: b.Location,
+		Path:     b.Path,
+	}
+}
+
+func (bs *BookmarkService) GetBookmarkFolders() ([]*BookmarkFolder, error) {
+	var folders []*BookmarkFolder
+	if err := database.db.Order("sort, id").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+func (bs *BookmarkService) GetBookmarks() ([]*Bookmark, error) {
+	var bookmarks []*Bookmark
+	if err := database.db.Order("sort, id").Find(&bookmarks).Error; err != nil {
+		return nil, err
+	}
+
+	return bookmarks, nil
+}
+
+func (bs *BookmarkService) AddBookmark(name string, ctx *pb.FileContext, folderId *uint) (*Bookmark, error) {
+	if name == "" {
+		if name = path.Base(ctx.Path); name == "/" || name == "." {
+			name = ctx.Location
+		}
+	}
+
+	sort, err := bs.nextBookmarkSort(database.db, folderId)
+	if err != nil {
+		return nil, err
+	}
+
+	model := Bookmark{
+		Name:     name,
+		FolderId: folderId,
+		Sort:     sort,
+		NodeId:   ctx.NodeId,
+		Location: ctx.Location,
+		Path:     ctx.Path,
+	}
+
+	if err := database.db.Create(&model).Error; err != nil {
+		return nil, err
+	}
+
+	bs.emitChanged()
+	return &model, nil
+}
+
+func (bs *BookmarkService) RenameBookmark(id uint, name string) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+
+	if err := database.db.Model(&Bookmark{}).Where("id = ?", id).Update("name", name).Error; err != nil {
+		return err
+	}
+
+	bs.emitChanged()
+	return nil
+}
+
+// MoveBookmark moves the bookmark into the folder (nil for the top level) at
+// the given position, the other bookmarks of that folder are shifted down.
+func (bs *BookmarkService) MoveBookmark(id uint, folderId *uint, sort int) error {
+	err := database.db.Transaction(func(tx *gorm.DB) error {
+		var bookmarks []*Bookmark
+		if err := bs.folderScope(tx, folderId).Where("id <> ?", id).Order("sort, id").Find(&bookmarks).Error; err != nil {
+			return err
+		}
+
+		sort = max(0, min(sort, len(bookmarks)))
+		for i, item := range bookmarks {
+			position := i
+			if i >= sort {
+				position++
+			}
+
+			if err := tx.Model(item).Update("sort", position).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&Bookmark{}).Where("id = ?", id).Updates(map[string]any{
+			"folder_id": folderId,
+			"sort":      sort,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	bs.emitChanged()
+	return nil
+}
+
+func (bs *BookmarkService) RemoveBookmark(id uint) error {
+	if err := database.db.Unscoped().Delete(&Bookmark{}, id).Error; err != nil {
+		return err
+	}
+
+	bs.emitChanged()
+	return nil
+}
+
+func (bs *BookmarkService) AddBookmarkFolder(name string) (*BookmarkFolder, error) {
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	var count int64
+	if err := database.db.Model(&BookmarkFolder{}).Count(&count).Error; err != nil {
+		return nil, err
+	}
+
+	folder := BookmarkFolder{Name: name, Sort: int(count)}
+	if err := database.db.Create(&folder).Error; err != nil {
+		return nil, err
+	}
+
+	bs.emitChanged()
+	return &folder, nil
+}
+
+func (bs *BookmarkService) RenameBookmarkFolder(id uint, name string) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+
+	if err := database.db.Model(&BookmarkFolder{}).Where("id = ?", id).Update("name", name).Error; err != nil {
+		return err
+	}
+
+	bs.emitChanged()
+	return nil
+}
+
+func (bs *BookmarkService) MoveBookmarkFolder(id uint, sort int) error {
+	err := database.db.Transaction(func(tx *gorm.DB) error {
+		var folders []*BookmarkFolder
+		if err := tx.Where("id <> ?", id).Order("sort, id").Find(&folders).Error; err != nil {
+			return err
+		}
+
+		sort = max(0, min(sort, len(folders)))
+		for i, item := range folders {
+			position := i
+			if i >= sort {
+				position++
+			}
+
+			if err := tx.Model(item).Update("sort", position).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&BookmarkFolder{}).Where("id = ?", id).Update("sort", sort).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	bs.emitChanged()
+	return nil
+}
+
+// RemoveBookmarkFolder removes the folder, its bookmarks are moved to the top level.
+func (bs *BookmarkService) RemoveBookmarkFolder(id uint) error {
+	err := database.db.Transaction(func(tx *gorm.DB) error {
+		sort, err := bs.nextBookmarkSort(tx, nil)
+		if err != nil {
+			return err
+		}
+
+		var bookmarks []*Bookmark
+		if err := tx.Where("folder_id = ?", id).Order("sort, id").Find(&bookmarks).Error; err != nil {
+			return err
+		}
+
+		for i, item := range bookmarks {
+			if err := tx.Model(item).Updates(map[string]any{"folder_id": nil, "sort": sort + i}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Unscoped().Delete(&BookmarkFolder{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	bs.emitChanged()
+	return nil
+}
+
+// ValidateBookmarks stats every bookmark and flags those whose target is gone.
+// Bookmarks on offline nodes are flagged offline instead.
+func (bs *BookmarkService) ValidateBookmarks() ([]*Bookmark, error) {
+	bookmarks, err := bs.GetBookmarks()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := node.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	online := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		online[n.Id] = n.Status == pb.NodeStatus_ONLINE
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, 4)
+	now := time.Now()
+
+	for _, item := range bookmarks {
+		item.CheckedAt = &now
+		if item.Offline = !online[item.NodeId]; item.Offline {
+			item.Broken = false
+			continue
+		}
+
+		wg.Add(1)
+		go func(item *Bookmark) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			_, err := file.StatFile(item.fileContext())
+			<-sem
+
+			item.Broken = err != nil
+		}(item)
+	}
+	wg.Wait()
+
+	for _, item := range bookmarks {
+		if err := database.db.Model(item).Updates(map[string]any{
+			"broken":     item.Broken,
+			"offline":    item.Offline,
+			"checked_at": item.CheckedAt,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	bs.emitChanged()
+	return bookmarks, nil
+}
+
+// OpenBookmark asks the frontend to navigate to the bookmarked path.
+func (bs *BookmarkService) OpenBookmark(id uint) error {
+	var model Bookmark
+	if err := database.db.First(&model, id).Error; err != nil {
+		return err
+	}
+
+	application.Get().EmitEvent(EventBookmarkOpen, model.fileContext())
+	return nil
+}
+
+func (bs *BookmarkService) folderScope(tx *gorm.DB, folderId *uint) *gorm.DB {
+	if folderId == nil {
+		return tx.Where("folder_id IS NULL")
+	}
+
+	return tx.Where("folder_id = ?", *folderId)
+}
+
+func (bs *BookmarkService) nextBookmarkSort(tx *gorm.DB, folderId *uint) (int, error) {
+	var count int64
+	if err := bs.folderScope(tx.Model(&Bookmark{}), folderId).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+func (bs *BookmarkService) emitChanged() {
+	application.Get().EmitEvent(EventBookmarksChanged)
+}
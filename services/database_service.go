@@ -54,7 +54,33 @@ func (d *DatabaseService) OnStartup(ctx context.Context, _ application.ServiceOp
 		return err
 	}
 
-	if err = d.db.AutoMigrate(&TransportManager{}); err != nil {
+	if err = d.db.AutoMigrate(
+		&TransportManager{},
+		&SearchEntry{},
+		&BookmarkFolder{},
+		&Bookmark{},
+		&Activity{},
+		&TrashItem{},
+		&JournalEntry{},
+		&MoveTask{},
+		&MoveTaskItem{},
+		&UsageSnapshot{},
+		&UsageEntry{},
+		&UsageBucket{},
+		&BackupJob{},
+		&BackupRun{},
+		&BackupFileState{},
+		&WatchFolder{},
+		&WatchUpload{},
+		&WatchFileState{},
+		&InboxFolder{},
+		&InboxEntry{},
+		&SyncRun{},
+		&SyncSetting{},
+		&SyncConflict{},
+		&SyncSchedule{},
+		&SyncTemplate{},
+	); err != nil {
 		return err
 	}
 
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+const (
+	EventDuplicateScanProgress = "duplicates:progress"
+	EventDuplicateScanFinished = "duplicates:finished"
+
+	duplicateHashConcurrency = 4
+)
+
+// DuplicateService finds files with the same content across nodes. Files are
+// grouped by size first so only the candidates have to be hashed.
+type DuplicateService struct {
+	mu     sync.Mutex
+	scan   *DuplicateScan
+	cancel context.CancelFunc
+}
+
+type DuplicateScan struct {
+	Status          string // running, success, failed, cancelled
+	Scopes          []*pb.FileContext
+	Scanned         int
+	Candidates      int
+	Hashed          int
+	Groups          []*DuplicateGroup
+	ReclaimableSize int64
+	Errors          []string
+	StartedAt       time.Time
+	FinishedAt      *time.Time
+}
+
+// DuplicateGroup is a set of files with the same size and hash, keeping one of
+// them reclaims Size * (len(Files) - 1) bytes.
+type DuplicateGroup struct {
+	Hash            string
+	Size            int64
+	Files           []*DuplicateFile
+	ReclaimableSize int64
+}
+
+type duplicateKey struct {
+	size int64
+	hash string
+}
+
+type DuplicateFile struct {
+	Context    *pb.FileContext
+	Name       string
+	ModifiedAt time.Time
+}
+
+var duplicate *DuplicateService
+var onceDuplicate sync.Once
+
+func NewDuplicateService() *DuplicateService {
+	if duplicate == nil {
+		onceDuplicate.Do(func() {
+			duplicate = &DuplicateService{}
+		})
+	}
+
+	return duplicate
+}
+
+// FindDuplicates walks the given directories in the background, the result is
+// returned by GetDuplicateScan and the finished event.
+func (d *DuplicateService) FindDuplicates(scopes []*pb.FileContext) (*DuplicateScan, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("no locations selected")
+	}
+
+	d.mu.Lock()
+	if d.scan != nil && d.scan.Status == "running" {
+		d.mu.Unlock()
+		return nil, errors.New("duplicate scan is running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d.scan = &DuplicateScan{Status: "running", Scopes: scopes, StartedAt: time.Now()}
+	d.cancel = cancel
+	d.mu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		err := d.run(ctx, scopes)
+
+		d.mu.Lock()
+		switch {
+		case ctx.Err() != nil:
+			d.scan.Status = "cancelled"
+		case err != nil:
+			d.scan.Status = "failed"
+			d.scan.Errors = append(d.scan.Errors, err.Error())
+		default:
+			d.scan.Status = "success"
+		}
+
+		now := time.Now()
+		d.scan.FinishedAt = &now
+		d.mu.Unlock()
+
+		application.Get().EmitEvent(EventDuplicateScanFinished, d.GetDuplicateScan())
+	}()
+
+	return d.GetDuplicateScan(), nil
+}
+
+func (d *DuplicateService) GetDuplicateScan() *DuplicateScan {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.scan == nil {
+		return nil
+	}
+
+	copied := *d.scan
+	copied.Groups = slices.Clone(d.scan.Groups)
+	copied.Errors = slices.Clone(d.scan.Errors)
+	return &copied
+}
+
+func (d *DuplicateService) CancelDuplicateScan() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// RemoveDuplicates moves the selected files into the trash as one batch job.
+func (d *DuplicateService) RemoveDuplicates(ctxs []*pb.FileContext) (*BatchJob, error) {
+	items := make([]*BatchJobItem, 0, len(ctxs))
+	for _, ctx := range ctxs {
+		items = append(items, &BatchJobItem{Src: ctx})
+	}
+
+	return batch.startJob("remove", items, d.removeItem)
+}
+
+// KeepOneDuplicate removes every file of the group identified by hash except keep.
+func (d *DuplicateService) KeepOneDuplicate(hash string, keep *pb.FileContext) (*BatchJob, error) {
+	d.mu.Lock()
+	var group *DuplicateGroup
+	if d.scan != nil {
+		for _, g := range d.scan.Groups {
+			if g.Hash == hash {
+				group = g
+				break
+			}
+		}
+	}
+	d.mu.Unlock()
+
+	if group == nil {
+		return nil, fmt.Errorf("duplicate group %s not found", hash)
+	}
+
+	var items []*BatchJobItem
+	var kept bool
+	for _, f := range group.Files {
+		if sameFileContext(f.Context, keep) {
+			kept = true
+			continue
+		}
+
+		items = append(items, &BatchJobItem{Src: f.Context})
+	}
+
+	if !kept {
+		return nil, errors.New("the file to keep is not part of the group")
+	}
+
+	return batch.startJob("remove", items, d.removeItem)
+}
+
+func (d *DuplicateService) removeItem(item *BatchJobItem) error {
+	if err := file.RemoveFile(item.Src); err != nil {
+		return err
+	}
+
+	d.forget(item.Src)
+	return nil
+}
+
+// forget drops a removed file from the last scan result.
+func (d *DuplicateService) forget(ctx *pb.FileContext) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.scan == nil {
+		return
+	}
+
+	var groups []*DuplicateGroup
+	var reclaimable int64
+	for _, g := range d.scan.Groups {
+		files := slices.DeleteFunc(slices.Clone(g.Files), func(f *DuplicateFile) bool {
+			return sameFileContext(f.Context, ctx)
+		})
+
+		if len(files) < 2 {
+			continue
+		}
+
+		group := &DuplicateGroup{Hash: g.Hash, Size: g.Size, Files: files, ReclaimableSize: g.Size * int64(len(files)-1)}
+		groups = append(groups, group)
+		reclaimable += group.ReclaimableSize
+	}
+
+	d.scan.Groups = groups
+	d.scan.ReclaimableSize = reclaimable
+}
+
+func (d *DuplicateService) run(ctx context.Context, scopes []*pb.FileContext) error {
+	bySize := make(map[int64][]*DuplicateFile)
+	seen := make(map[string]bool)
+
+	for _, scope := range scopes {
+		err := file.walkFiles(scope, func(relPath string, fileInfo *pb.File) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if fileInfo.Type == pb.FileType_DIR || fileInfo.Size == 0 {
+				return nil
+			}
+
+			fileCtx := &pb.FileContext{
+				NodeId:   scope.NodeId,
+				Location: scope.Location,
+				Path:     path.Join(scope.Path, relPath),
+			}
+
+			// overlapping scopes must not report a file as its own duplicate
+			key := fileCtx.NodeId + ":" + fileCtx.Location + ":" + fileCtx.Path
+			if seen[key] {
+				return nil
+			}
+			seen[key] = true
+
+			bySize[fileInfo.Size] = append(bySize[fileInfo.Size], &DuplicateFile{
+				Context:    fileCtx,
+				Name:       fileInfo.Name,
+				ModifiedAt: fileInfo.ModifiedAt.AsTime(),
+			})
+
+			d.mu.Lock()
+			d.scan.Scanned++
+			d.mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			d.addError(fmt.Sprintf("%s/%s%s: %s", scope.NodeId, scope.Location, scope.Path, err))
+		}
+	}
+
+	var candidates []*DuplicateFile
+	sizes := make(map[*DuplicateFile]int64)
+	for size, files := range bySize {
+		if len(files) < 2 {
+			continue
+		}
+
+		for _, f := range files {
+			candidates = append(candidates, f)
+			sizes[f] = size
+		}
+	}
+
+	d.mu.Lock()
+	d.scan.Candidates = len(candidates)
+	d.mu.Unlock()
+	application.Get().EmitEvent(EventDuplicateScanProgress, d.GetDuplicateScan())
+
+	var wg sync.WaitGroup
+	var hashMu sync.Mutex
+	semaphore := make(chan struct{}, duplicateHashConcurrency)
+	byHash := make(map[duplicateKey][]*DuplicateFile)
+
+	for _, candidate := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			stat, err := file.statFile(candidate.Context)
+			if err != nil {
+				d.addError(fmt.Sprintf("%s: %s", candidate.Context.Path, err))
+				return
+			}
+
+			if stat.Hash != "" {
+				key := duplicateKey{size: sizes[candidate], hash: stat.Hash}
+				hashMu.Lock()
+				byHash[key] = append(byHash[key], candidate)
+				hashMu.Unlock()
+			}
+
+			d.mu.Lock()
+			d.scan.Hashed++
+			d.mu.Unlock()
+			application.Get().EmitEvent(EventDuplicateScanProgress, d.GetDuplicateScan())
+		}()
+	}
+	wg.Wait()
+
+	var groups []*DuplicateGroup
+	var reclaimable int64
+	for key, files := range byHash {
+		if len(files) < 2 {
+			continue
+		}
+
+		sort.Slice(files, func(i, j int) bool { return files[i].ModifiedAt.Before(files[j].ModifiedAt) })
+		group := &DuplicateGroup{Hash: key.hash, Size: key.size, Files: files, ReclaimableSize: key.size * int64(len(files)-1)}
+		groups = append(groups, group)
+		reclaimable += group.ReclaimableSize
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ReclaimableSize > groups[j].ReclaimableSize })
+
+	d.mu.Lock()
+	d.scan.Groups = groups
+	d.scan.ReclaimableSize = reclaimable
+	d.mu.Unlock()
+
+	return nil
+}
+
+func (d *DuplicateService) addError(msg string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.scan.Errors = append(d.scan.Errors, msg)
+}
+
+func sameFileContext(a, b *pb.FileContext) bool {
+	return a.NodeId == b.NodeId && a.Location == b.Location && path.Clean(a.Path) == path.Clean(b.Path)
+}
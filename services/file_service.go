@@ -5,9 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +18,7 @@ import (
 	"connectrpc.com/connect"
 	"github.com/pixelfs/pixelfs/config"
 	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
 	"github.com/pixelfs/pixelfs/util"
 	"github.com/wailsapp/wails/v3/pkg/application"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -46,6 +50,16 @@ func NewFileService() *FileService {
 }
 
 func (f *FileService) GetFileList(ctx *pb.FileContext) ([]*pb.File, error) {
+	files, err := f.listFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recordActivity(ActivityNavigate, ctx, nil)
+	return files, nil
+}
+
+func (f *FileService) listFiles(ctx *pb.FileContext) ([]*pb.File, error) {
 	response, err := rpc.FileSystemService.List(
 		context.Background(),
 		connect.NewRequest(&pb.FileListRequest{
@@ -57,7 +71,50 @@ func (f *FileService) GetFileList(ctx *pb.FileContext) ([]*pb.File, error) {
 		return nil, err
 	}
 
-	return response.Msg.GetFiles(), nil
+	files := response.Msg.GetFiles()
+	if path.Clean("/"+ctx.Path) == "/" {
+		files = slices.DeleteFunc(files, func(fileInfo *pb.File) bool {
+			return fileInfo.Name == trashDirName
+		})
+	}
+
+	return files, nil
+}
+
+// walkFiles calls fn for every file and directory below ctx, parents before
+// their children, with the path relative to ctx. If fn returns fs.SkipDir for
+// a directory its contents are skipped.
+func (f *FileService) walkFiles(ctx *pb.FileContext, fn func(relPath string, fileInfo *pb.File) error) error {
+	var walk func(relPath string) error
+	walk = func(relPath string) error {
+		files, err := f.listFiles(&pb.FileContext{
+			NodeId:   ctx.NodeId,
+			Location: ctx.Location,
+			Path:     path.Join(ctx.Path, relPath),
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, fileInfo := range files {
+			childPath := path.Join(relPath, fileInfo.Name)
+			if err := fn(childPath, fileInfo); errors.Is(err, fs.SkipDir) {
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			if fileInfo.Type == pb.FileType_DIR {
+				if err := walk(childPath); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	return walk("")
 }
 
 func (f *FileService) StatFile(ctx *pb.FileContext) (*pb.File, error) {
@@ -75,7 +132,43 @@ func (f *FileService) StatFile(ctx *pb.FileContext) (*pb.File, error) {
 	return response.Msg.GetFile(), nil
 }
 
+// statFile is StatFile with the hash of the file computed by its node.
+func (f *FileService) statFile(ctx *pb.FileContext) (*pb.File, error) {
+	response, err := rpc.FileSystemService.Stat(
+		context.Background(),
+		connect.NewRequest(&pb.FileStatRequest{
+			Context: ctx,
+			Hash:    true,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Msg.GetFile(), nil
+}
+
+// RemoveFile moves the file into the trash of its location, files that are
+// already in the trash are removed permanently.
 func (f *FileService) RemoveFile(ctx *pb.FileContext) error {
+	if isInTrash(ctx.Path) {
+		if err := f.removeFile(ctx); err != nil {
+			return err
+		}
+	} else {
+		item, err := trash.trashFile(ctx)
+		if err != nil {
+			return err
+		}
+
+		recordJournal(ActivityDelete, ctx, item.trashContext(), &item.ID)
+	}
+
+	recordActivity(ActivityDelete, ctx, nil)
+	return nil
+}
+
+func (f *FileService) removeFile(ctx *pb.FileContext) error {
 	_, err := rpc.FileSystemService.Remove(
 		context.Background(),
 		connect.NewRequest(&pb.FileRemoveRequest{
@@ -90,6 +183,28 @@ func (f *FileService) RemoveFile(ctx *pb.FileContext) error {
 	return nil
 }
 
+// mkdirAll creates the directory and any missing parents.
+func (f *FileService) mkdirAll(ctx *pb.FileContext) error {
+	dir := path.Clean("/" + ctx.Path)
+	if dir == "/" {
+		return nil
+	}
+
+	if stat, err := f.StatFile(&pb.FileContext{NodeId: ctx.NodeId, Location: ctx.Location, Path: dir}); err == nil {
+		if stat.Type != pb.FileType_DIR {
+			return fmt.Errorf("%s is not a directory", dir)
+		}
+
+		return nil
+	}
+
+	if err := f.mkdirAll(&pb.FileContext{NodeId: ctx.NodeId, Location: ctx.Location, Path: path.Dir(dir)}); err != nil {
+		return err
+	}
+
+	return f.Mkdir(&pb.FileContext{NodeId: ctx.NodeId, Location: ctx.Location, Path: dir})
+}
+
 func (f *FileService) Mkdir(ctx *pb.FileContext) error {
 	_, err := rpc.FileSystemService.Mkdir(
 		context.Background(),
@@ -112,33 +227,53 @@ func (f *FileService) RenameFile(src *pb.FileContext, dest *pb.FileContext) erro
 			Dest: dest,
 		}),
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	recordActivity(ActivityRename, src, dest)
+	recordJournal(ActivityRename, src, dest, nil)
+	return nil
 }
 
 func (f *FileService) MoveFile(src *pb.FileContext, dest *pb.FileContext) error {
 	go func() {
-		if err := f.copyFile(src, dest); err != nil {
+		if err := f.moveFile(src, dest); err != nil {
 			f.showErrorDialog("文件移动错误", err.Error())
 			return
 		}
 
-		_, err := rpc.FileSystemService.Remove(
-			context.Background(),
-			connect.NewRequest(&pb.FileRemoveRequest{
-				Context:   src,
-				Recursive: true,
-			}),
-		)
-
-		if err != nil {
-			f.showErrorDialog("文件移动错误", err.Error())
-		}
+		recordActivity(ActivityMove, src, dest)
 	}()
 
 	return nil
 }
 
+func (f *FileService) moveFile(src *pb.FileContext, dest *pb.FileContext) error {
+	// between nodes every file is verified before its source is removed
+	if src.NodeId != dest.NodeId {
+		return move.moveFile(src, dest)
+	}
+
+	if err := f.copyFile(src, f.cloneContext(dest)); err != nil {
+		return err
+	}
+
+	_, err := rpc.FileSystemService.Remove(
+		context.Background(),
+		connect.NewRequest(&pb.FileRemoveRequest{
+			Context:   src,
+			Recursive: true,
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	recordJournal(ActivityMove, src, dest, nil)
+	return nil
+}
+
 func (f *FileService) CopyFile(src *pb.FileContext, dest *pb.FileContext) error {
 	go func() {
 		if err := f.copyFile(src, dest); err != nil {
@@ -149,6 +284,264 @@ func (f *FileService) CopyFile(src *pb.FileContext, dest *pb.FileContext) error
 	return nil
 }
 
+// RemoveFiles moves the files into the trash as one tracked batch job.
+func (f *FileService) RemoveFiles(ctxs []*pb.FileContext) (*BatchJob, error) {
+	items := make([]*BatchJobItem, 0, len(ctxs))
+	for _, ctx := range ctxs {
+		items = append(items, &BatchJobItem{Src: ctx})
+	}
+
+	return batch.startJob("remove", items, func(item *BatchJobItem) error {
+		return f.RemoveFile(item.Src)
+	})
+}
+
+// MoveFiles moves the files into the destination directory as one tracked batch job.
+func (f *FileService) MoveFiles(srcs []*pb.FileContext, destDir *pb.FileContext) (*BatchJob, error) {
+	return batch.startJob("move", f.batchItems(srcs, destDir), func(item *BatchJobItem) error {
+		if err := f.moveFile(item.Src, item.Dest); err != nil {
+			return err
+		}
+
+		recordActivity(ActivityMove, item.Src, item.Dest)
+		return nil
+	})
+}
+
+// CopyFiles copies the files into the destination directory as one tracked batch job.
+func (f *FileService) CopyFiles(srcs []*pb.FileContext, destDir *pb.FileContext) (*BatchJob, error) {
+	return batch.startJob("copy", f.batchItems(srcs, destDir), func(item *BatchJobItem) error {
+		return f.copyFile(item.Src, f.cloneContext(item.Dest))
+	})
+}
+
+// DownloadFiles downloads the files into a local directory chosen by the user
+// as one tracked batch job.
+func (f *FileService) DownloadFiles(ctxs []*pb.FileContext) (*BatchJob, error) {
+	downloadPath, err := preferences.GetDownloadPath()
+	if err != nil {
+		return nil, err
+	}
+
+	dialog := application.OpenFileDialog()
+	dialog.SetOptions(&application.OpenFileDialogOptions{
+		Title:                "Download Files",
+		Directory:            downloadPath,
+		ShowHiddenFiles:      true,
+		CanCreateDirectories: true,
+		CanChooseDirectories: true,
+	})
+
+	outputDir, err := dialog.PromptForSingleSelection()
+	if err != nil {
+		return nil, err
+	}
+
+	if outputDir == "" {
+		return nil, errors.New("cancel")
+	}
+
+	downloadThreads, err := preferences.GetDownloadThreads()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*BatchJobItem, 0, len(ctxs))
+	for _, ctx := range ctxs {
+		_, fileName := filepath.Split(ctx.Path)
+		items = append(items, &BatchJobItem{Src: ctx, LocalPath: filepath.Join(outputDir, fileName)})
+	}
+
+	return batch.startJob("download", items, func(item *BatchJobItem) error {
+		return f.downloadFile(item.Src, item.LocalPath, downloadThreads)
+	})
+}
+
+func (f *FileService) batchItems(srcs []*pb.FileContext, destDir *pb.FileContext) []*BatchJobItem {
+	items := make([]*BatchJobItem, 0, len(srcs))
+	for _, src := range srcs {
+		_, fileName := filepath.Split(src.Path)
+		items = append(items, &BatchJobItem{
+			Src: src,
+			Dest: &pb.FileContext{
+				NodeId:   destDir.NodeId,
+				Location: destDir.Location,
+				Path:     filepath.ToSlash(filepath.Join(destDir.Path, fileName)),
+			},
+		})
+	}
+
+	return items
+}
+
+type FileComparison struct {
+	Src       *pb.FileContext
+	Dest      *pb.FileContext
+	Added     []*FileDiff // only in src
+	Removed   []*FileDiff // only in dest
+	Changed   []*FileDiff
+	Unchanged int
+}
+
+// FileDiff is a difference at Path relative to the compared directories. Of an
+// added or removed directory only the directory itself is reported.
+type FileDiff struct {
+	Path   string
+	Reason string // type, size, mtime, hash for changed entries
+	Src    *pb.File
+	Dest   *pb.File
+}
+
+// Compare lists both directories recursively and reports how src differs from
+// dest. Files of the same size are compared by mtime, or by hash if requested.
+func (f *FileService) Compare(src *pb.FileContext, dest *pb.FileContext, hash bool) (*FileComparison, error) {
+	return f.compare(src, dest, hash)
+}
+
+// CopyDifferences copies the entries at the relative paths from src to dest as
+// one tracked batch job.
+func (f *FileService) CopyDifferences(src *pb.FileContext, dest *pb.FileContext, paths []string) (*BatchJob, error) {
+	items := make([]*BatchJobItem, 0, len(paths))
+	for _, p := range paths {
+		items = append(items, &BatchJobItem{
+			Src:  &pb.FileContext{NodeId: src.NodeId, Location: src.Location, Path: path.Join(src.Path, p)},
+			Dest: &pb.FileContext{NodeId: dest.NodeId, Location: dest.Location, Path: path.Join(dest.Path, p)},
+		})
+	}
+
+	return batch.startJob("copy", items, func(item *BatchJobItem) error {
+		if err := f.mkdirAll(&pb.FileContext{
+			NodeId:   item.Dest.NodeId,
+			Location: item.Dest.Location,
+			Path:     path.Dir(item.Dest.Path),
+		}); err != nil {
+			return err
+		}
+
+		return f.copyFile(item.Src, f.cloneContext(item.Dest))
+	})
+}
+
+// compare diffs the two trees.
+func (f *FileService) compare(src *pb.FileContext, dest *pb.FileContext, hash bool) (*FileComparison, error) {
+	tree := func(ctx *pb.FileContext) (map[string]*pb.File, error) {
+		files := make(map[string]*pb.File)
+		err := f.walkFiles(ctx, func(relPath string, fileInfo *pb.File) error {
+			files[relPath] = fileInfo
+			return nil
+		})
+
+		return files, err
+	}
+
+	srcFiles, err := tree(src)
+	if err != nil {
+		return nil, err
+	}
+
+	destFiles, err := tree(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &FileComparison{Src: src, Dest: dest}
+
+	// entries below an added or removed directory are covered by the directory
+	covered := func(relPath string, other map[string]*pb.File) bool {
+		parent := path.Dir(relPath)
+		if parent == "." {
+			return false
+		}
+
+		_, ok := other[parent]
+		return !ok
+	}
+
+	var hashCandidates []*FileDiff
+	for relPath, srcFile := range srcFiles {
+		destFile, ok := destFiles[relPath]
+		switch {
+		case !ok:
+			if !covered(relPath, destFiles) {
+				comparison.Added = append(comparison.Added, &FileDiff{Path: relPath, Src: srcFile})
+			}
+		case (srcFile.Type == pb.FileType_DIR) != (destFile.Type == pb.FileType_DIR):
+			comparison.Changed = append(comparison.Changed, &FileDiff{Path: relPath, Reason: "type", Src: srcFile, Dest: destFile})
+		case srcFile.Type == pb.FileType_DIR:
+			comparison.Unchanged++
+		case srcFile.Size != destFile.Size:
+			comparison.Changed = append(comparison.Changed, &FileDiff{Path: relPath, Reason: "size", Src: srcFile, Dest: destFile})
+		case hash:
+			hashCandidates = append(hashCandidates, &FileDiff{Path: relPath, Reason: "hash", Src: srcFile, Dest: destFile})
+		case srcFile.ModifiedAt.AsTime().Unix() != destFile.ModifiedAt.AsTime().Unix():
+			comparison.Changed = append(comparison.Changed, &FileDiff{Path: relPath, Reason: "mtime", Src: srcFile, Dest: destFile})
+		default:
+			comparison.Unchanged++
+		}
+	}
+
+	for relPath, destFile := range destFiles {
+		if _, ok := srcFiles[relPath]; !ok && !covered(relPath, srcFiles) {
+			comparison.Removed = append(comparison.Removed, &FileDiff{Path: relPath, Dest: destFile})
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var errs error
+	semaphore := make(chan struct{}, 4)
+
+	for _, diff := range hashCandidates {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			srcStat, err := f.statFile(&pb.FileContext{NodeId: src.NodeId, Location: src.Location, Path: path.Join(src.Path, diff.Path)})
+			if err == nil {
+				var destStat *pb.File
+				if destStat, err = f.statFile(&pb.FileContext{NodeId: dest.NodeId, Location: dest.Location, Path: path.Join(dest.Path, diff.Path)}); err == nil {
+					diff.Src, diff.Dest = srcStat, destStat
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err != nil:
+				errs = errors.Join(errs, err)
+			case diff.Src.Hash != diff.Dest.Hash:
+				comparison.Changed = append(comparison.Changed, diff)
+			default:
+				comparison.Unchanged++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if errs != nil {
+		return nil, errs
+	}
+
+	for _, diffs := range [][]*FileDiff{comparison.Added, comparison.Removed, comparison.Changed} {
+		slices.SortFunc(diffs, func(a, b *FileDiff) int { return strings.Compare(a.Path, b.Path) })
+	}
+
+	return comparison, nil
+}
+
+// cloneContext returns a copy of ctx, copyFile rewrites the destination path
+// while it writes to the temporary file.
+func (f *FileService) cloneContext(ctx *pb.FileContext) *pb.FileContext {
+	return &pb.FileContext{
+		NodeId:   ctx.NodeId,
+		Location: ctx.Location,
+		Path:     ctx.Path,
+	}
+}
+
 func (f *FileService) copyFile(src *pb.FileContext, dest *pb.FileContext) (err error) {
 	if src.NodeId == dest.NodeId {
 		copyModel := TransportManager{
@@ -254,7 +647,7 @@ func (f *FileService) copyFile(src *pb.FileContext, dest *pb.FileContext) (err e
 				}),
 			)
 		} else {
-			_ = f.RemoveFile(dest)
+			_ = f.removeFile(dest)
 		}
 	}()
 
@@ -619,6 +1012,30 @@ func (f *FileService) UploadFile(ctx *pb.FileContext) error {
 	return nil
 }
 
+// uploadLocalFile uploads a local file to ctx and tracks it in the transport manager.
+func (f *FileService) uploadLocalFile(ctx *pb.FileContext, inputFilePath string) error {
+	ctx = f.cloneContext(ctx)
+	uploadModel := TransportManager{
+		Type:     "upload",
+		NodeId:   ctx.NodeId,
+		Location: ctx.Location,
+		Path:     ctx.Path,
+		Status:   "uploading",
+		Progress: 0,
+	}
+
+	if err := database.db.Create(&uploadModel).Error; err != nil {
+		return err
+	}
+
+	if err := f.uploadFile(ctx, inputFilePath, &uploadModel); err != nil {
+		database.db.Model(&uploadModel).Update("status", "failed")
+		return err
+	}
+
+	return nil
+}
+
 func (f *FileService) uploadFile(ctx *pb.FileContext, inputFilePath string, uploadModel *TransportManager) (err error) {
 	fileDir, fileName := filepath.Split(ctx.Path)
 	ctx.Path = filepath.ToSlash(filepath.Join(fileDir, tmpPrefix+fileName))
@@ -637,7 +1054,7 @@ func (f *FileService) uploadFile(ctx *pb.FileContext, inputFilePath string, uplo
 				}),
 			)
 		} else {
-			_ = f.RemoveFile(ctx)
+			_ = f.removeFile(ctx)
 		}
 	}()
 
@@ -730,19 +1147,19 @@ func (f *FileService) uploadFile(ctx *pb.FileContext, inputFilePath string, uplo
 }
 
 func (f *FileService) PlayVideo(ctx *pb.FileContext) error {
-	response, err := rpc.FileSystemService.M3U8(
-		context.Background(),
-		connect.NewRequest(&pb.FileM3U8Request{
-			Context:       ctx,
-			BlockSettings: &pb.BlockSettings{},
-		}),
-	)
-
+	playback, err := f.GetVideoPlayback(ctx)
 	if err != nil {
 		return err
 	}
 
-	signature, err := f.extractSignature(response.Msg.Url)
+	recordActivity(ActivityPlay, ctx, nil)
+
+	// play through the local player when subtitle tracks need to be attached
+	if len(playback.Subtitles) > 0 {
+		return application.Get().BrowserOpenURL(playback.PlayerUrl)
+	}
+
+	signature, err := f.extractSignature(playback.StreamUrl)
 	if err != nil {
 		return err
 	}
@@ -755,6 +1172,43 @@ func (f *FileService) PlayVideo(ctx *pb.FileContext) error {
 	return application.Get().BrowserOpenURL(cfg.Endpoint + "/player/" + signature)
 }
 
+// GetVideoPlayback prepares an HLS stream for the video together with the
+// subtitle files found next to it. The returned playlist url can be opened by
+// any HLS capable player to get the subtitle tracks. Subtitles that can't be
+// looked up are left out.
+func (f *FileService) GetVideoPlayback(ctx *pb.FileContext) (*VideoPlayback, error) {
+	response, err := rpc.FileSystemService.M3U8(
+		context.Background(),
+		connect.NewRequest(&pb.FileM3U8Request{
+			Context:       ctx,
+			BlockSettings: &pb.BlockSettings{},
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	playback := &VideoPlayback{Name: path.Base(ctx.Path), StreamUrl: response.Msg.Url}
+
+	// the video still plays without its name, duration and subtitles
+	if stat, err := f.StatFile(ctx); err != nil {
+		log.Warn().Err(err).Msgf("failed to stat video %s", ctx.Path)
+	} else {
+		playback.Name = stat.Name
+		playback.Duration = float64(stat.Duration)
+	}
+
+	if playback.Subtitles, err = findSubtitles(ctx); err != nil {
+		log.Warn().Err(err).Msgf("failed to find subtitles of %s", ctx.Path)
+	}
+
+	if err = media.registerVideo(playback); err != nil {
+		return nil, err
+	}
+
+	return playback, nil
+}
+
 func (f *FileService) extractSignature(m3u8 string) (string, error) {
 	parsedURL, err := url.Parse(m3u8)
 	if err != nil {
@@ -765,6 +1219,143 @@ func (f *FileService) extractSignature(m3u8 string) (string, error) {
 	return parts[2], nil
 }
 
+// remoteFile reads a remote file block by block and implements io.ReadSeeker,
+// keeping the most recently fetched block in memory.
+type remoteFile struct {
+	ctx       *pb.FileContext
+	file      *pb.File
+	size      int64
+	blockSize int64
+	offset    int64
+
+	blockIndex int64
+	block      []byte
+}
+
+func (f *FileService) openFile(ctx *pb.FileContext) (*remoteFile, error) {
+	stat, err := rpc.FileSystemService.Stat(
+		context.Background(),
+		connect.NewRequest(&pb.FileStatRequest{
+			Context: ctx,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if stat.Msg.File.Type == pb.FileType_DIR {
+		return nil, fmt.Errorf("%s is a directory", ctx.Path)
+	}
+
+	locationRsp, err := rpc.LocationService.GetLocationByContext(
+		context.Background(),
+		connect.NewRequest(&pb.GetLocationByContextRequest{
+			Context: ctx,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteFile{
+		ctx:        ctx,
+		file:       stat.Msg.File,
+		size:       stat.Msg.File.Size,
+		blockSize:  locationRsp.Msg.Location.BlockSize,
+		blockIndex: -1,
+	}, nil
+}
+
+func (f *FileService) readFile(ctx *pb.FileContext) ([]byte, error) {
+	reader, err := f.openFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(reader)
+}
+
+func (r *remoteFile) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	index := r.offset / r.blockSize
+	if index != r.blockIndex {
+		block, err := r.readBlock(index)
+		if err != nil {
+			return 0, err
+		}
+
+		r.block = block
+		r.blockIndex = index
+	}
+
+	start := r.offset - index*r.blockSize
+	if start >= int64(len(r.block)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	n := copy(p, r.block[start:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+func (r *remoteFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *remoteFile) readBlock(index int64) ([]byte, error) {
+	var err error
+	var read *connect.Response[pb.FileReadResponse]
+
+	for retries := 0; retries < 20; retries++ {
+		read, err = rpc.FileSystemService.Read(
+			context.Background(),
+			connect.NewRequest(&pb.FileReadRequest{
+				Context:    r.ctx,
+				BlockType:  pb.BlockType_SIZE,
+				BlockIndex: index,
+			}),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read block %d: %w", index, err)
+		}
+
+		if read.Msg.BlockStatus != pb.BlockStatus_PENDING {
+			break
+		}
+
+		time.Sleep(5 * time.Second)
+	}
+
+	if read == nil || read.Msg.BlockStatus == pb.BlockStatus_PENDING {
+		return nil, fmt.Errorf("block %d is still pending after retries", index)
+	}
+
+	resp, err := util.Resty.R().Get(read.Msg.Url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download block %d: %w", index, err)
+	}
+
+	return resp.Body(), nil
+}
+
 func (f *FileService) showErrorDialog(title string, msg string) {
 	application.ErrorDialog().SetTitle(title).SetMessage(msg).Show()
 }
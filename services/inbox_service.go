@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	EventInboxDownloaded = "inbox:downloaded"
+
+	inboxDefaultInterval = 60
+	inboxMinInterval     = 10
+)
+
+// InboxService polls remote directories and downloads every new file into a
+// local folder. Downloaded files are remembered by path and hash, so nothing
+// is downloaded twice.
+type InboxService struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	polling map[uint]bool
+}
+
+type InboxFolder struct {
+	gorm.Model
+
+	Name         string
+	NodeId       string
+	Location     string
+	Path         string
+	LocalPath    string
+	Recursive    bool
+	Interval     int // seconds between polls
+	Enabled      bool
+	LastPolledAt *time.Time
+	LastError    string
+}
+
+// InboxEntry is a remote file that was downloaded, RemoteSize and
+// RemoteModifiedAt avoid hashing files that were seen before. LocalSize and
+// LocalModifiedAt tell whether the local copy was changed since.
+type InboxEntry struct {
+	gorm.Model
+
+	FolderId         uint   `gorm:"uniqueIndex:idx_inbox_entry"`
+	Path             string `gorm:"uniqueIndex:idx_inbox_entry"`
+	Hash             string `gorm:"uniqueIndex:idx_inbox_entry"`
+	RemoteSize       int64
+	RemoteModifiedAt time.Time
+	LocalPath        string
+	LocalSize        int64
+	LocalModifiedAt  time.Time
+	Status           string // success, failed
+	Error            string
+}
+
+var inbox *InboxService
+var onceInbox sync.Once
+
+func NewInboxService() *InboxService {
+	if inbox == nil {
+		onceInbox.Do(func() {
+			inbox = &InboxService{polling: make(map[uint]bool)}
+		})
+	}
+
+	return inbox
+}
+
+func (i *InboxService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	i.ctx = ctx
+
+	go i.schedule(ctx)
+	return nil
+}
+
+func (i *InboxService) schedule(ctx context.Context) {
+	ticker := time.NewTicker(inboxMinInterval * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if auth.loggedIn() {
+			var folders []*InboxFolder
+			if err := database.db.Where("enabled = ?", true).Find(&folders).Error; err != nil {
+				log.Error().Err(err).Msg("failed to load inbox folders")
+			}
+
+			for _, folder := range folders {
+				interval := folder.Interval
+				if interval <= 0 {
+					interval = inboxDefaultInterval
+				}
+
+				if folder.LastPolledAt == nil || time.Since(*folder.LastPolledAt) >= time.Duration(interval)*time.Second {
+					go i.poll(folder)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (i *InboxService) GetInboxFolders() ([]*InboxFolder, error) {
+	var folders []*InboxFolder
+	if err := database.db.Order("id").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+func (i *InboxService) CreateInboxFolder(folder *InboxFolder) (*InboxFolder, error) {
+	if err := i.validate(folder); err != nil {
+		return nil, err
+	}
+
+	folder.ID = 0
+	folder.LastPolledAt = nil
+	if err := database.db.Create(folder).Error; err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+func (i *InboxService) UpdateInboxFolder(folder *InboxFolder) (*InboxFolder, error) {
+	if err := i.validate(folder); err != nil {
+		return nil, err
+	}
+
+	var current InboxFolder
+	if err := database.db.First(&current, folder.ID).Error; err != nil {
+		return nil, err
+	}
+
+	folder.CreatedAt = current.CreatedAt
+	folder.LastPolledAt = current.LastPolledAt
+	if err := database.db.Save(folder).Error; err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+func (i *InboxService) RemoveInboxFolder(id uint) error {
+	return database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("folder_id = ?", id).Delete(&InboxEntry{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&InboxFolder{}, id).Error
+	})
+}
+
+func (i *InboxService) GetInboxEntries(folderId uint, limit int) ([]*InboxEntry, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var entries []*InboxEntry
+	if err := database.db.Where("folder_id = ?", folderId).Order("id desc").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// PollInboxFolder checks the folder right away.
+func (i *InboxService) PollInboxFolder(id uint) error {
+	var folder InboxFolder
+	if err := database.db.First(&folder, id).Error; err != nil {
+		return err
+	}
+
+	go i.poll(&folder)
+	return nil
+}
+
+func (i *InboxService) validate(folder *InboxFolder) error {
+	if folder.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if folder.NodeId == "" || folder.Location == "" {
+		return errors.New("remote directory is required")
+	}
+
+	if folder.Interval != 0 && folder.Interval < inboxMinInterval {
+		return fmt.Errorf("interval must be at least %d seconds", inboxMinInterval)
+	}
+
+	if err := os.MkdirAll(folder.LocalPath, 0755); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (i *InboxService) poll(folder *InboxFolder) {
+	i.mu.Lock()
+	if i.polling[folder.ID] {
+		i.mu.Unlock()
+		return
+	}
+	i.polling[folder.ID] = true
+	i.mu.Unlock()
+
+	defer func() {
+		i.mu.Lock()
+		delete(i.polling, folder.ID)
+		i.mu.Unlock()
+	}()
+
+	var lastError string
+	if err := i.fetch(folder); err != nil {
+		lastError = err.Error()
+		log.Warn().Err(err).Msgf("failed to poll inbox %s", folder.Name)
+	}
+
+	database.db.Model(folder).Updates(map[string]any{"last_polled_at": time.Now(), "last_error": lastError})
+}
+
+func (i *InboxService) fetch(folder *InboxFolder) error {
+	remote := &pb.FileContext{NodeId: folder.NodeId, Location: folder.Location, Path: folder.Path}
+
+	files := make(map[string]*pb.File)
+	if folder.Recursive {
+		if err := file.walkFiles(remote, func(relPath string, fileInfo *pb.File) error {
+			files[relPath] = fileInfo
+			return nil
+		}); err != nil {
+			return err
+		}
+	} else {
+		list, err := file.listFiles(remote)
+		if err != nil {
+			return err
+		}
+
+		for _, fileInfo := range list {
+			files[fileInfo.Name] = fileInfo
+		}
+	}
+
+	var entries []*InboxEntry
+	if err := database.db.Where("folder_id = ?", folder.ID).Find(&entries).Error; err != nil {
+		return err
+	}
+
+	// failed downloads are tried again
+	seen := make(map[string]bool, len(entries))
+	downloaded := make(map[string]*InboxEntry, len(entries))
+	for _, entry := range entries {
+		if entry.Status != "success" {
+			continue
+		}
+
+		if last, ok := downloaded[entry.Path]; !ok || entry.UpdatedAt.After(last.UpdatedAt) {
+			downloaded[entry.Path] = entry
+		}
+
+		seen[entry.Path+":"+entry.Hash] = true
+		seen[fmt.Sprintf("%s:%d:%d", entry.Path, entry.RemoteSize, entry.RemoteModifiedAt.Unix())] = true
+	}
+
+	var errs error
+	for relPath, fileInfo := range files {
+		// files that are still being uploaded
+		if fileInfo.Type == pb.FileType_DIR || strings.HasPrefix(fileInfo.Name, tmpPrefix) {
+			continue
+		}
+
+		modifiedAt := fileInfo.ModifiedAt.AsTime()
+		if seen[fmt.Sprintf("%s:%d:%d", relPath, fileInfo.Size, modifiedAt.Unix())] {
+			continue
+		}
+
+		ctx := &pb.FileContext{NodeId: remote.NodeId, Location: remote.Location, Path: path.Join(remote.Path, relPath)}
+		stat, err := file.statFile(ctx)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		entry := InboxEntry{
+			FolderId:         folder.ID,
+			Path:             relPath,
+			Hash:             stat.Hash,
+			RemoteSize:       fileInfo.Size,
+			RemoteModifiedAt: modifiedAt,
+			LocalPath:        filepath.Join(folder.LocalPath, filepath.FromSlash(relPath)),
+			Status:           "success",
+		}
+
+		if seen[relPath+":"+stat.Hash] {
+			// only touched, remember the new size and mtime
+			database.db.Model(&InboxEntry{}).
+				Where("folder_id = ? AND path = ? AND hash = ?", folder.ID, relPath, stat.Hash).
+				Updates(map[string]any{"remote_size": entry.RemoteSize, "remote_modified_at": entry.RemoteModifiedAt})
+			continue
+		}
+
+		info, err := i.download(ctx, entry.LocalPath, downloaded[relPath])
+		if err != nil {
+			entry.Status = "failed"
+			entry.Error = err.Error()
+			errs = errors.Join(errs, err)
+		} else {
+			entry.LocalSize = info.Size()
+			entry.LocalModifiedAt = info.ModTime()
+		}
+
+		if err := database.db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "folder_id"}, {Name: "path"}, {Name: "hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"remote_size", "remote_modified_at", "local_path", "local_size", "local_modified_at", "status", "error", "updated_at",
+			}),
+		}).Create(&entry).Error; err != nil {
+			return err
+		}
+
+		application.Get().EmitEvent(EventInboxDownloaded, &entry)
+	}
+
+	return errs
+}
+
+// download writes to a temporary file first, so a partial download is never
+// mistaken for the complete file. A local file that is not the last download
+// of the path, or that was changed since, is kept as a conflict copy.
+func (i *InboxService) download(ctx *pb.FileContext, localPath string, last *InboxEntry) (os.FileInfo, error) {
+	threads, err := preferences.GetDownloadThreads()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, name := filepath.Split(localPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	tmpPath := filepath.Join(dir, tmpPrefix+name)
+	if err := file.downloadFile(ctx, tmpPath, threads); err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+
+	info, err := os.Stat(localPath)
+	if err == nil && (last == nil || info.Size() != last.LocalSize || !info.ModTime().Equal(last.LocalModifiedAt)) {
+		ext := filepath.Ext(localPath)
+		conflictPath := strings.TrimSuffix(localPath, ext) + ".conflict-" + time.Now().Format("20060102-150405") + ext
+		if err := os.Rename(localPath, conflictPath); err != nil {
+			_ = os.Remove(tmpPath)
+			return nil, err
+		}
+	}
+
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		return nil, err
+	}
+
+	return os.Stat(localPath)
+}
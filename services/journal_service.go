@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"gorm.io/gorm"
+)
+
+const journalMaxEntries = 100
+
+// JournalService records reversible operations so they can be undone and redone.
+type JournalService struct {
+	mu sync.Mutex
+}
+
+// JournalEntry describes a file that was moved from Src to Dest on one node.
+// Size and ModifiedAt describe the file where it currently is, they are used
+// to refuse undo or redo when it has been changed since. Files are not hashed,
+// that would read the whole file or tree just to journal a rename.
+type JournalEntry struct {
+	gorm.Model
+
+	Type         string // rename, move, delete
+	NodeId       string
+	SrcLocation  string
+	SrcPath      string
+	DestLocation string
+	DestPath     string
+	TrashItemId  *uint
+
+	Size       int64
+	ModifiedAt time.Time
+	Undone     bool
+}
+
+var journal *JournalService
+var onceJournal sync.Once
+
+func NewJournalService() *JournalService {
+	if journal == nil {
+		onceJournal.Do(func() {
+			journal = &JournalService{}
+		})
+	}
+
+	return journal
+}
+
+func (e *JournalEntry) srcContext() *pb.FileContext {
+	return &pb.FileContext{NodeId: e.NodeId, Location: e.SrcLocation, Path: e.SrcPath}
+}
+
+func (e *JournalEntry) destContext() *pb.FileContext {
+	return &pb.FileContext{NodeId: e.NodeId, Location: e.DestLocation, Path: e.DestPath}
+}
+
+func (j *JournalService) GetJournal(limit int) ([]*JournalEntry, error) {
+	if limit <= 0 {
+		limit = journalMaxEntries
+	}
+
+	var entries []*JournalEntry
+	if err := database.db.Order("id desc").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (j *JournalService) CanUndo() (bool, error) {
+	var count int64
+	err := database.db.Model(&JournalEntry{}).Where("undone = ?", false).Count(&count).Error
+	return count > 0, err
+}
+
+func (j *JournalService) CanRedo() (bool, error) {
+	var count int64
+	err := database.db.Model(&JournalEntry{}).Where("undone = ?", true).Count(&count).Error
+	return count > 0, err
+}
+
+// Undo reverts the most recent operation.
+func (j *JournalService) Undo() (*JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entry JournalEntry
+	if err := database.db.Where("undone = ?", false).Order("id desc").First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("nothing to undo")
+		}
+
+		return nil, err
+	}
+
+	if err := j.apply(&entry, entry.destContext(), entry.srcContext()); err != nil {
+		return nil, err
+	}
+
+	if entry.TrashItemId != nil {
+		if err := database.db.Delete(&TrashItem{}, *entry.TrashItemId).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	entry.Undone = true
+	if err := database.db.Save(&entry).Error; err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// Redo applies the most recently undone operation again.
+func (j *JournalService) Redo() (*JournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var entry JournalEntry
+	if err := database.db.Where("undone = ?", true).Order("id").First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("nothing to redo")
+		}
+
+		return nil, err
+	}
+
+	if err := j.apply(&entry, entry.srcContext(), entry.destContext()); err != nil {
+		return nil, err
+	}
+
+	if entry.TrashItemId != nil {
+		if err := database.db.Unscoped().Model(&TrashItem{}).Where("id = ?", *entry.TrashItemId).
+			Updates(map[string]any{"deleted_at": nil, "trashed_at": time.Now()}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	entry.Undone = false
+	if err := database.db.Save(&entry).Error; err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (j *JournalService) ClearJournal() error {
+	return database.db.Unscoped().Where("1 = 1").Delete(&JournalEntry{}).Error
+}
+
+// apply moves the file from -> to after checking that it is unchanged since
+// it was journaled and that nothing occupies the target.
+func (j *JournalService) apply(entry *JournalEntry, from, to *pb.FileContext) error {
+	current, err := file.StatFile(from)
+	if err != nil {
+		return fmt.Errorf("%s no longer exists: %w", from.Path, err)
+	}
+
+	if current.Size != entry.Size || !current.ModifiedAt.AsTime().Equal(entry.ModifiedAt) {
+		return fmt.Errorf("%s has been changed since the %s", from.Path, entry.Type)
+	}
+
+	if _, err := file.StatFile(to); err == nil {
+		return fmt.Errorf("%s already exists", to.Path)
+	}
+
+	_, err = rpc.FileSystemService.Move(
+		context.Background(),
+		connect.NewRequest(&pb.FileMoveRequest{
+			Src:  from,
+			Dest: to,
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	return j.snapshot(entry, to)
+}
+
+// record journals an operation whose result is at dest, which also clears
+// the redo history.
+func (j *JournalService) record(typ string, src, dest *pb.FileContext, trashItemId *uint) error {
+	if src.NodeId != dest.NodeId {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry := JournalEntry{
+		Type:         typ,
+		NodeId:       src.NodeId,
+		SrcLocation:  src.Location,
+		SrcPath:      src.Path,
+		DestLocation: dest.Location,
+		DestPath:     dest.Path,
+		TrashItemId:  trashItemId,
+	}
+
+	if err := j.snapshot(&entry, dest); err != nil {
+		return err
+	}
+
+	return database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("undone = ?", true).Delete(&JournalEntry{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+
+		var ids []uint
+		if err := tx.Model(&JournalEntry{}).Order("id desc").Offset(journalMaxEntries).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		return tx.Unscoped().Delete(&JournalEntry{}, ids).Error
+	})
+}
+
+func (j *JournalService) snapshot(entry *JournalEntry, ctx *pb.FileContext) error {
+	stat, err := file.StatFile(ctx)
+	if err != nil {
+		return err
+	}
+
+	entry.Size = stat.Size
+	entry.ModifiedAt = stat.ModifiedAt.AsTime()
+	return nil
+}
+
+// recordJournal never fails the operation that is being journaled.
+func recordJournal(typ string, src, dest *pb.FileContext, trashItemId *uint) {
+	if err := journal.record(typ, src, dest, trashItemId); err != nil {
+		log.Warn().Err(err).Msgf("failed to journal %s", typ)
+	}
+}
@@ -43,17 +43,21 @@ func (l *LocationService) AddLocation(nodeId, name, path, blockSize string, bloc
 		return nil, err
 	}
 
+	return l.addLocation(&pb.Location{
+		NodeId:        nodeId,
+		Name:          name,
+		Type:          pb.LocationType_LOCAL,
+		Path:          path,
+		BlockSize:     int64(bSize),
+		BlockDuration: blockDuration,
+	})
+}
+
+func (l *LocationService) addLocation(loc *pb.Location) (*pb.AddLocationResponse, error) {
 	response, err := rpc.LocationService.AddLocation(
 		context.Background(),
 		connect.NewRequest(&pb.AddLocationRequest{
-			Location: &pb.Location{
-				NodeId:        nodeId,
-				Name:          name,
-				Type:          pb.LocationType_LOCAL,
-				Path:          path,
-				BlockSize:     int64(bSize),
-				BlockDuration: blockDuration,
-			},
+			Location: loc,
 		}),
 	)
 	if err != nil {
@@ -76,3 +80,13 @@ func (l *LocationService) RemoveLocation(locationId string) (*pb.RemoveLocationR
 
 	return response.Msg, nil
 }
+
+func findLocation(locations []*pb.Location, nodeId, name string) *pb.Location {
+	for _, loc := range locations {
+		if loc.NodeId == nodeId && loc.Name == name {
+			return loc
+		}
+	}
+
+	return nil
+}
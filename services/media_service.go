@@ -0,0 +1,505 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// MediaService runs a loopback HTTP server that serves media related
+// resources (subtitles, playlists, player pages) to the browser or to
+// external players.
+type MediaService struct {
+	ctx    context.Context
+	server *http.Server
+	mux    *http.ServeMux
+	addr   string
+
+	mu     sync.Mutex
+	videos map[string]*VideoPlayback
+}
+
+type VideoPlayback struct {
+	Id          string
+	Name        string
+	Duration    float64
+	StreamUrl   string
+	PlayerUrl   string
+	PlaylistUrl string
+	Subtitles   []*VideoSubtitle
+
+	accessedAt time.Time
+}
+
+type VideoSubtitle struct {
+	Name     string
+	Label    string
+	Language string
+	Format   string // srt, ass, ssa, vtt
+	Url      string
+	Context  *pb.FileContext
+
+	mu   sync.Mutex
+	data []byte
+}
+
+const videoPlaybackTTL = 12 * time.Hour
+
+var (
+	subtitleExtensions = []string{".srt", ".vtt", ".ass", ".ssa"}
+	srtTimingRegexp    = regexp.MustCompile(`(\d+:\d{2}:\d{2}),(\d{3})`)
+	assOverrideRegexp  = regexp.MustCompile(`\{[^}]*\}`)
+
+	// the frontend build, it contains the bundled hls.js of the player page
+	mediaAssets fs.FS
+
+	media     *MediaService
+	onceMedia sync.Once
+)
+
+func NewMediaService() *MediaService {
+	if media == nil {
+		onceMedia.Do(func() {
+			media = &MediaService{
+				mux:    http.NewServeMux(),
+				videos: make(map[string]*VideoPlayback),
+			}
+
+			media.mux.HandleFunc("GET /player/hls.min.js", media.handlePlayerScript)
+			media.mux.HandleFunc("GET /video/{id}/{$}", media.handleVideoPlayer)
+			media.mux.HandleFunc("GET /video/{id}/master.m3u8", media.handleVideoMaster)
+			media.mux.HandleFunc("GET /video/{id}/subtitles/{index}/playlist.m3u8", media.handleSubtitlePlaylist)
+			media.mux.HandleFunc("GET /video/{id}/subtitles/{index}/subtitle.vtt", media.handleSubtitle)
+		})
+	}
+
+	return media
+}
+
+// SetMediaAssets sets the frontend build the player page loads its scripts
+// from.
+func SetMediaAssets(assets fs.FS) {
+	mediaAssets = assets
+}
+
+func (m *MediaService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	m.ctx = ctx
+	m.addr = listener.Addr().String()
+	m.server = &http.Server{Handler: m.mux}
+
+	go func() {
+		if err := m.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("media server stopped")
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.pruneVideos()
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (m *MediaService) OnShutdown() error {
+	if m.server == nil {
+		return nil
+	}
+
+	return m.server.Close()
+}
+
+func (m *MediaService) baseUrl() string {
+	return "http://" + m.addr
+}
+
+func (m *MediaService) registerVideo(playback *VideoPlayback) error {
+	id, err := randomId()
+	if err != nil {
+		return err
+	}
+
+	playback.Id = id
+	playback.PlayerUrl = fmt.Sprintf("%s/video/%s/", m.baseUrl(), id)
+	playback.PlaylistUrl = fmt.Sprintf("%s/video/%s/master.m3u8", m.baseUrl(), id)
+	for i, subtitle := range playback.Subtitles {
+		subtitle.Url = fmt.Sprintf("%s/video/%s/subtitles/%d/subtitle.vtt", m.baseUrl(), id, i)
+	}
+
+	m.mu.Lock()
+	playback.accessedAt = time.Now()
+	m.videos[id] = playback
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *MediaService) getVideo(id string) (*VideoPlayback, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	playback, ok := m.videos[id]
+	if ok {
+		playback.accessedAt = time.Now()
+	}
+
+	return playback, ok
+}
+
+// pruneVideos forgets the videos that weren't requested for a while.
+func (m *MediaService) pruneVideos() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, playback := range m.videos {
+		if time.Since(playback.accessedAt) > videoPlaybackTTL {
+			delete(m.videos, id)
+		}
+	}
+}
+
+func (m *MediaService) getSubtitle(r *http.Request) (*VideoPlayback, *VideoSubtitle, bool) {
+	playback, ok := m.getVideo(r.PathValue("id"))
+	if !ok {
+		return nil, nil, false
+	}
+
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || index < 0 || index >= len(playback.Subtitles) {
+		return nil, nil, false
+	}
+
+	return playback, playback.Subtitles[index], true
+}
+
+var videoPlayerTemplate = template.Must(template.New("player").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>html,body{margin:0;height:100%;background:#000}video{width:100%;height:100%}</style>
+<script src="/player/hls.min.js"></script>
+</head>
+<body>
+<video id="video" controls autoplay crossorigin="anonymous">
+{{range $i, $s := .Subtitles}}<track kind="subtitles" src="subtitles/{{$i}}/subtitle.vtt" label="{{$s.Label}}"{{if $s.Language}} srclang="{{$s.Language}}"{{end}}{{if eq $i 0}} default{{end}}>
+{{end}}</video>
+<script>
+const video = document.getElementById("video");
+const src = {{.StreamUrl}};
+if (video.canPlayType("application/vnd.apple.mpegurl")) {
+  video.src = src;
+} else if (window.Hls && Hls.isSupported()) {
+  const hls = new Hls({ renderTextTracksNatively: true });
+  hls.loadSource(src);
+  hls.attachMedia(video);
+}
+</script>
+</body>
+</html>`))
+
+func (m *MediaService) handlePlayerScript(w http.ResponseWriter, r *http.Request) {
+	if mediaAssets == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeFileFS(w, r, mediaAssets, "player/hls.min.js")
+}
+
+func (m *MediaService) handleVideoPlayer(w http.ResponseWriter, r *http.Request) {
+	playback, ok := m.getVideo(r.PathValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := videoPlayerTemplate.Execute(w, playback); err != nil {
+		log.Error().Err(err).Msg("failed to render video player")
+	}
+}
+
+func (m *MediaService) handleVideoMaster(w http.ResponseWriter, r *http.Request) {
+	playback, ok := m.getVideo(r.PathValue("id"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	for i, subtitle := range playback.Subtitles {
+		fmt.Fprintf(&b, "#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",NAME=%q,", subtitle.Label)
+		if subtitle.Language != "" {
+			fmt.Fprintf(&b, "LANGUAGE=%q,", subtitle.Language)
+		}
+
+		isDefault := "NO"
+		if i == 0 {
+			isDefault = "YES"
+		}
+		fmt.Fprintf(&b, "DEFAULT=%s,AUTOSELECT=YES,URI=\"subtitles/%d/playlist.m3u8\"\n", isDefault, i)
+	}
+
+	b.WriteString("#EXT-X-STREAM-INF:BANDWIDTH=2000000")
+	if len(playback.Subtitles) > 0 {
+		b.WriteString(",SUBTITLES=\"subs\"")
+	}
+	b.WriteString("\n" + playback.StreamUrl + "\n")
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	_, _ = w.Write([]byte(b.String()))
+}
+
+func (m *MediaService) handleSubtitlePlaylist(w http.ResponseWriter, r *http.Request) {
+	playback, _, ok := m.getSubtitle(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// the whole subtitle is served as a single segment spanning the video
+	duration := playback.Duration
+	if duration <= 0 {
+		duration = 24 * 60 * 60
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	_, _ = fmt.Fprintf(w,
+		"#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:%.3f,\nsubtitle.vtt\n#EXT-X-ENDLIST\n",
+		int64(duration)+1, duration,
+	)
+}
+
+func (m *MediaService) handleSubtitle(w http.ResponseWriter, r *http.Request) {
+	_, subtitle, ok := m.getSubtitle(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := subtitle.load()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	_, _ = w.Write(data)
+}
+
+// load reads and converts the subtitle once, a failed attempt is retried on
+// the next request.
+func (s *VideoSubtitle) load() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data != nil {
+		return s.data, nil
+	}
+
+	data, err := file.readFile(s.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.data, err = convertSubtitle(s.Format, data); err != nil {
+		return nil, err
+	}
+
+	return s.data, nil
+}
+
+// findSubtitles looks for subtitle files next to the video whose names start
+// with the video base name, e.g. movie.srt, movie.en.srt or movie.zh-CN.ass.
+func findSubtitles(ctx *pb.FileContext) ([]*VideoSubtitle, error) {
+	dir, videoName := path.Split(ctx.Path)
+	files, err := file.listFiles(&pb.FileContext{
+		NodeId:   ctx.NodeId,
+		Location: ctx.Location,
+		Path:     dir,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	baseName := strings.TrimSuffix(videoName, path.Ext(videoName))
+
+	var subtitles []*VideoSubtitle
+	for _, fileInfo := range files {
+		if fileInfo.Type == pb.FileType_DIR {
+			continue
+		}
+
+		ext := strings.ToLower(path.Ext(fileInfo.Name))
+		if !slices.Contains(subtitleExtensions, ext) {
+			continue
+		}
+
+		name := strings.TrimSuffix(fileInfo.Name, path.Ext(fileInfo.Name))
+		if name != baseName && !strings.HasPrefix(name, baseName+".") {
+			continue
+		}
+
+		language := strings.TrimPrefix(strings.TrimPrefix(name, baseName), ".")
+		label := language
+		if label == "" {
+			label = fileInfo.Name
+		}
+
+		subtitles = append(subtitles, &VideoSubtitle{
+			Name:     fileInfo.Name,
+			Label:    label,
+			Language: language,
+			Format:   strings.TrimPrefix(ext, "."),
+			Context: &pb.FileContext{
+				NodeId:   ctx.NodeId,
+				Location: ctx.Location,
+				Path:     path.Join(dir, fileInfo.Name),
+			},
+		})
+	}
+
+	return subtitles, nil
+}
+
+func convertSubtitle(format string, data []byte) ([]byte, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	switch format {
+	case "vtt":
+		return []byte(text), nil
+	case "srt":
+		return []byte(srtToVtt(text)), nil
+	case "ass", "ssa":
+		return []byte(assToVtt(text)), nil
+	default:
+		return nil, fmt.Errorf("unsupported subtitle format: %s", format)
+	}
+}
+
+func srtToVtt(text string) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Contains(line, "-->") {
+			line = srtTimingRegexp.ReplaceAllString(line, "$1.$2")
+		}
+
+		b.WriteString(line + "\n")
+	}
+
+	return b.String()
+}
+
+func assToVtt(text string) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+
+	var inEvents bool
+	var format []string
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+
+		if !inEvents {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "Format":
+			format = strings.Split(value, ",")
+			for i := range format {
+				format[i] = strings.TrimSpace(format[i])
+			}
+		case "Dialogue":
+			fields := strings.SplitN(strings.TrimSpace(value), ",", len(format))
+			if len(format) == 0 || len(fields) != len(format) {
+				continue
+			}
+
+			event := make(map[string]string, len(fields))
+			for i, name := range format {
+				event[name] = fields[i]
+			}
+
+			cue := assOverrideRegexp.ReplaceAllString(event["Text"], "")
+			cue = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ").Replace(cue)
+			if strings.TrimSpace(cue) == "" {
+				continue
+			}
+
+			fmt.Fprintf(&b, "%s --> %s\n%s\n\n", assTimeToVtt(event["Start"]), assTimeToVtt(event["End"]), cue)
+		}
+	}
+
+	return b.String()
+}
+
+// assTimeToVtt converts H:MM:SS.cc to HH:MM:SS.mmm.
+func assTimeToVtt(value string) string {
+	clock, fraction, _ := strings.Cut(strings.TrimSpace(value), ".")
+	parts := strings.Split(clock, ":")
+	if len(parts) != 3 {
+		return "00:00:00.000"
+	}
+
+	hours, _ := strconv.Atoi(parts[0])
+	centis, _ := strconv.Atoi((fraction + "00")[:2])
+	return fmt.Sprintf("%02d:%s:%s.%03d", hours, parts[1], parts[2], centis*10)
+}
+
+func randomId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import "testing"
+
+func TestSrtToVtt(t *testing.T) {
+	tests := []struct {
+		name string
+		srt  string
+		want string
+	}{
+		{
+			"cue",
+			"1\n00:00:01,000 --> 00:00:02,500\nHello\n",
+			"WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.500\nHello\n\n",
+		},
+		{
+			"commas in text",
+			"2\n01:02:03,004 --> 01:02:04,005\n1,000 people\n",
+			"WEBVTT\n\n2\n01:02:03.004 --> 01:02:04.005\n1,000 people\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := srtToVtt(tt.srt); got != tt.want {
+				t.Errorf("srtToVtt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssTimeToVtt(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"0:00:01.50", "00:00:01.500"},
+		{"1:02:03.4", "01:02:03.400"},
+		{" 0:00:01.05 ", "00:00:01.050"},
+		{"0:00:01", "00:00:01.000"},
+		{"bad", "00:00:00.000"},
+	}
+
+	for _, tt := range tests {
+		if got := assTimeToVtt(tt.value); got != tt.want {
+			t.Errorf("assTimeToVtt(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAssToVtt(t *testing.T) {
+	tests := []struct {
+		name string
+		ass  string
+		want string
+	}{
+		{
+			"dialogue",
+			"[Script Info]\nTitle: x\n\n[Events]\n" +
+				"Format: Layer, Start, End, Style, Text\n" +
+				"Dialogue: 0,0:00:01.00,0:00:02.00,Default,{\\b1}Hello\\NWorld, again\n",
+			"WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\nWorld, again\n\n",
+		},
+		{
+			"only overrides",
+			"[Events]\nFormat: Layer, Start, End, Style, Text\nDialogue: 0,0:00:01.00,0:00:02.00,Default,{\\pos(1,2)}\n",
+			"WEBVTT\n\n",
+		},
+		{
+			"dialogue before format",
+			"[Events]\nDialogue: 0,0:00:01.00,0:00:02.00,Default,Hello\n",
+			"WEBVTT\n\n",
+		},
+		{
+			"outside events",
+			"[Script Info]\nFormat: Layer, Start, End, Style, Text\nDialogue: 0,0:00:01.00,0:00:02.00,Default,Hello\n",
+			"WEBVTT\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assToVtt(tt.ass); got != tt.want {
+				t.Errorf("assToVtt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertSubtitle(t *testing.T) {
+	tests := []struct {
+		format  string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"vtt", "\ufeffWEBVTT\r\n\r\n", "WEBVTT\n\n", false},
+		{"srt", "1\r\n00:00:01,000 --> 00:00:02,000\r\nHi\r\n", "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nHi\n\n", false},
+		{"ssa", "[Events]\nFormat: Start, End, Text\nDialogue: 0:00:01.00,0:00:02.00,Hi\n", "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHi\n\n", false},
+		{"sub", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := convertSubtitle(tt.format, []byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("convertSubtitle() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("convertSubtitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
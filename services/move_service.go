@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+)
+
+// MoveService moves files between nodes one file at a time. Every file is
+// verified at the destination before its source is removed and the progress
+// is persisted, so an interrupted move can be resumed.
+type MoveService struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	running map[uint]bool
+}
+
+type MoveTask struct {
+	gorm.Model
+
+	SrcNodeId    string
+	SrcLocation  string
+	SrcPath      string
+	DestNodeId   string
+	DestLocation string
+	DestPath     string
+
+	Status string // pending, running, interrupted, success, failed
+	Total  int
+	Done   int
+	Error  string
+}
+
+type MoveTaskItem struct {
+	gorm.Model
+
+	TaskId  uint `gorm:"index"`
+	RelPath string
+	IsDir   bool
+	Size    int64
+	Status  string // pending, copied, removed, failed
+	Error   string
+}
+
+var move *MoveService
+var onceMove sync.Once
+
+func NewMoveService() *MoveService {
+	if move == nil {
+		onceMove.Do(func() {
+			move = &MoveService{running: make(map[uint]bool)}
+		})
+	}
+
+	return move
+}
+
+func (m *MoveService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	m.ctx = ctx
+
+	// tasks that were running when the application quit can be resumed
+	return database.db.Model(&MoveTask{}).
+		Where("status IN ?", []string{"pending", "running"}).
+		Update("status", "interrupted").Error
+}
+
+func (m *MoveService) GetMoveTasks() ([]*MoveTask, error) {
+	var tasks []*MoveTask
+	if err := database.db.Order("id desc").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (m *MoveService) GetMoveTaskItems(id uint) ([]*MoveTaskItem, error) {
+	var items []*MoveTaskItem
+	if err := database.db.Where("task_id = ?", id).Order("rel_path").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// ResumeMoveTask continues an interrupted or failed move in the background.
+func (m *MoveService) ResumeMoveTask(id uint) error {
+	var task MoveTask
+	if err := database.db.First(&task, id).Error; err != nil {
+		return err
+	}
+
+	if task.Status == "success" {
+		return errors.New("move task is already finished")
+	}
+
+	go func() {
+		if err := m.run(&task); err != nil {
+			file.showErrorDialog("文件移动错误", err.Error())
+		}
+	}()
+
+	return nil
+}
+
+func (m *MoveService) RemoveMoveTask(id uint) error {
+	m.mu.Lock()
+	running := m.running[id]
+	m.mu.Unlock()
+
+	if running {
+		return errors.New("move task is running")
+	}
+
+	return database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("task_id = ?", id).Delete(&MoveTaskItem{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&MoveTask{}, id).Error
+	})
+}
+
+// moveFile creates a move task and runs it until it finishes.
+func (m *MoveService) moveFile(src, dest *pb.FileContext) error {
+	task := MoveTask{
+		SrcNodeId:    src.NodeId,
+		SrcLocation:  src.Location,
+		SrcPath:      src.Path,
+		DestNodeId:   dest.NodeId,
+		DestLocation: dest.Location,
+		DestPath:     dest.Path,
+		Status:       "pending",
+	}
+
+	if err := database.db.Create(&task).Error; err != nil {
+		return err
+	}
+
+	return m.run(&task)
+}
+
+func (m *MoveService) run(task *MoveTask) error {
+	m.mu.Lock()
+	if m.running[task.ID] {
+		m.mu.Unlock()
+		return errors.New("move task is already running")
+	}
+	m.running[task.ID] = true
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, task.ID)
+		m.mu.Unlock()
+	}()
+
+	if err := database.db.Model(task).Updates(map[string]any{"status": "running", "error": ""}).Error; err != nil {
+		return err
+	}
+
+	err := m.process(task)
+	if err != nil {
+		database.db.Model(task).Updates(map[string]any{"status": "failed", "error": err.Error()})
+		return err
+	}
+
+	return database.db.Model(task).Update("status", "success").Error
+}
+
+func (m *MoveService) process(task *MoveTask) error {
+	var items []*MoveTaskItem
+	if err := database.db.Where("task_id = ?", task.ID).Order("rel_path").Find(&items).Error; err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		var err error
+		if items, err = m.enumerate(task); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range items {
+		if !item.IsDir || item.Status == "removed" {
+			continue
+		}
+
+		if err := file.mkdirAll(m.destContext(task, item)); err != nil {
+			return err
+		}
+	}
+
+	var failed []string
+	for _, item := range items {
+		if item.IsDir || item.Status == "removed" {
+			continue
+		}
+
+		if err := m.moveItem(task, item); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", path.Join(task.SrcPath, item.RelPath), err))
+			database.db.Model(item).Updates(map[string]any{"status": "failed", "error": err.Error()})
+			continue
+		}
+
+		if err := database.db.Model(task).Update("done", gorm.Expr("done + 1")).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d files were not moved:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+
+	// every file has been moved, remove the now empty source directories deepest first
+	sort.Slice(items, func(i, j int) bool { return len(items[i].RelPath) > len(items[j].RelPath) })
+	for _, item := range items {
+		if !item.IsDir || item.Status == "removed" {
+			continue
+		}
+
+		if _, err := rpc.FileSystemService.Remove(
+			context.Background(),
+			connect.NewRequest(&pb.FileRemoveRequest{
+				Context: m.srcContext(task, item),
+			}),
+		); err != nil {
+			return fmt.Errorf("failed to remove directory %s: %w", m.srcContext(task, item).Path, err)
+		}
+
+		database.db.Model(item).Update("status", "removed")
+	}
+
+	return nil
+}
+
+// enumerate lists the source tree and stores an item for every file and directory.
+func (m *MoveService) enumerate(task *MoveTask) ([]*MoveTaskItem, error) {
+	src := &pb.FileContext{NodeId: task.SrcNodeId, Location: task.SrcLocation, Path: task.SrcPath}
+	stat, err := file.StatFile(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*MoveTaskItem
+	if stat.Type != pb.FileType_DIR {
+		items = append(items, &MoveTaskItem{TaskId: task.ID, Size: stat.Size, Status: "pending"})
+	} else {
+		items = append(items, &MoveTaskItem{TaskId: task.ID, IsDir: true, Status: "pending"})
+		err = file.walkFiles(src, func(relPath string, fileInfo *pb.File) error {
+			items = append(items, &MoveTaskItem{
+				TaskId:  task.ID,
+				RelPath: relPath,
+				IsDir:   fileInfo.Type == pb.FileType_DIR,
+				Size:    fileInfo.Size,
+				Status:  "pending",
+			})
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var total int
+	for _, item := range items {
+		if !item.IsDir {
+			total++
+		}
+	}
+
+	err = database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(items, 500).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(task).Update("total", total).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].RelPath < items[j].RelPath })
+	return items, nil
+}
+
+func (m *MoveService) moveItem(task *MoveTask, item *MoveTaskItem) error {
+	src := m.srcContext(task, item)
+	dest := m.destContext(task, item)
+
+	if item.Status != "copied" {
+		if err := file.copyFile(src, file.cloneContext(dest)); err != nil {
+			return err
+		}
+	}
+
+	srcStat, err := file.statFile(src)
+	if err != nil {
+		return err
+	}
+
+	destStat, err := file.statFile(dest)
+	if err != nil {
+		return fmt.Errorf("failed to verify the copy: %w", err)
+	}
+
+	if srcStat.Size != destStat.Size || srcStat.Hash != destStat.Hash {
+		// copy again on the next attempt
+		database.db.Model(item).Update("status", "pending")
+		return errors.New("the copy does not match the source")
+	}
+
+	if err := database.db.Model(item).Update("status", "copied").Error; err != nil {
+		return err
+	}
+
+	if _, err = rpc.FileSystemService.Remove(
+		context.Background(),
+		connect.NewRequest(&pb.FileRemoveRequest{
+			Context: src,
+		}),
+	); err != nil {
+		return err
+	}
+
+	return database.db.Model(item).Updates(map[string]any{"status": "removed", "error": ""}).Error
+}
+
+func (m *MoveService) srcContext(task *MoveTask, item *MoveTaskItem) *pb.FileContext {
+	return &pb.FileContext{
+		NodeId:   task.SrcNodeId,
+		Location: task.SrcLocation,
+		Path:     path.Join(task.SrcPath, item.RelPath),
+	}
+}
+
+func (m *MoveService) destContext(task *MoveTask, item *MoveTaskItem) *pb.FileContext {
+	return &pb.FileContext{
+		NodeId:   task.DestNodeId,
+		Location: task.DestLocation,
+		Path:     path.Join(task.DestPath, item.RelPath),
+	}
+}
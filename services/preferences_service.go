@@ -68,3 +68,84 @@ func (p *PreferencesService) GetDownloadThreads() (int, error) {
 func (p *PreferencesService) SetDownloadThreads(threads int) error {
 	return localStorage.SetLocalStorage("downloadThreads", threads)
 }
+
+func (p *PreferencesService) GetTrashRetentionDays() (int, error) {
+	retentionDays, err := localStorage.GetLocalStorage("trashRetentionDays")
+	if err != nil {
+		return 0, err
+	}
+
+	if retentionDays != nil {
+		return int(retentionDays.(float64)), nil
+	}
+
+	return 30, nil
+}
+
+func (p *PreferencesService) SetTrashRetentionDays(days int) error {
+	return localStorage.SetLocalStorage("trashRetentionDays", days)
+}
+
+func (p *PreferencesService) GetPauseSyncOnBattery() (bool, error) {
+	pauseOnBattery, err := localStorage.GetLocalStorage("pauseSyncOnBattery")
+	if err != nil {
+		return false, err
+	}
+
+	if pauseOnBattery != nil {
+		return pauseOnBattery.(bool), nil
+	}
+
+	return false, nil
+}
+
+func (p *PreferencesService) SetPauseSyncOnBattery(pause bool) error {
+	return localStorage.SetLocalStorage("pauseSyncOnBattery", pause)
+}
+
+// GetPauseSyncNetworks returns the names of the Wi-Fi networks on which all
+// syncs are paused.
+func (p *PreferencesService) GetPauseSyncNetworks() ([]string, error) {
+	networks, err := localStorage.GetLocalStorage("pauseSyncNetworks")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	if values, ok := networks.([]any); ok {
+		for _, value := range values {
+			if name, ok := value.(string); ok {
+				result = append(result, name)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (p *PreferencesService) SetPauseSyncNetworks(networks []string) error {
+	if networks == nil {
+		networks = []string{}
+	}
+
+	return localStorage.SetLocalStorage("pauseSyncNetworks", networks)
+}
+
+// GetSyncHealthNotifications reports whether unhealthy syncs raise desktop
+// notifications, which they do unless turned off.
+func (p *PreferencesService) GetSyncHealthNotifications() (bool, error) {
+	notifications, err := localStorage.GetLocalStorage("syncHealthNotifications")
+	if err != nil {
+		return false, err
+	}
+
+	if notifications != nil {
+		return notifications.(bool), nil
+	}
+
+	return true, nil
+}
+
+func (p *PreferencesService) SetSyncHealthNotifications(enabled bool) error {
+	return localStorage.SetLocalStorage("syncHealthNotifications", enabled)
+}
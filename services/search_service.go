@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SearchService struct {
+	ctx context.Context
+
+	mu     sync.Mutex
+	status SearchIndexStatus
+}
+
+type SearchEntry struct {
+	ID         uint   `gorm:"primarykey"`
+	NodeId     string `gorm:"uniqueIndex:idx_search_entry_path"`
+	Location   string `gorm:"uniqueIndex:idx_search_entry_path"`
+	Path       string `gorm:"uniqueIndex:idx_search_entry_path"`
+	Name       string
+	Extension  string `gorm:"index"`
+	Type       pb.FileType
+	Size       int64     `gorm:"index"`
+	ModifiedAt time.Time `gorm:"index"`
+	IndexedAt  time.Time
+}
+
+type SearchIndexStatus struct {
+	Running    bool
+	Locations  int
+	Files      int
+	Errors     []string
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+type SearchQuery struct {
+	Keyword        string   // full-text match on name and path
+	NameGlob       string   // e.g. *.mp4, report-202?-*
+	Extensions     []string // without the leading dot
+	NodeId         string
+	Location       string
+	Type           *pb.FileType
+	MinSize        int64
+	MaxSize        int64
+	ModifiedAfter  *time.Time
+	ModifiedBefore *time.Time
+	Limit          int
+	Offset         int
+}
+
+type searchIndexedFile struct {
+	id         uint
+	size       int64
+	modifiedAt time.Time
+}
+
+const (
+	searchConcurrency = 4
+	searchBatchSize   = 500
+)
+
+var search *SearchService
+var onceSearch sync.Once
+
+func NewSearchService() *SearchService {
+	if search == nil {
+		onceSearch.Do(func() {
+			search = &SearchService{}
+		})
+	}
+
+	return search
+}
+
+func (s *SearchService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS search_entries_fts USING fts4(content="search_entries", name, path)`,
+		`CREATE TRIGGER IF NOT EXISTS search_entries_ai AFTER INSERT ON search_entries BEGIN
+			INSERT INTO search_entries_fts(docid, name, path) VALUES (new.id, new.name, new.path);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_entries_bd BEFORE DELETE ON search_entries BEGIN
+			DELETE FROM search_entries_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_entries_bu BEFORE UPDATE ON search_entries BEGIN
+			DELETE FROM search_entries_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_entries_au AFTER UPDATE ON search_entries BEGIN
+			INSERT INTO search_entries_fts(docid, name, path) VALUES (new.id, new.name, new.path);
+		END`,
+	}
+
+	for _, statement := range statements {
+		if err := database.db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	s.ctx = ctx
+	return nil
+}
+
+func (s *SearchService) GetIndexStatus() SearchIndexStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.status
+}
+
+// RefreshIndex crawls every location of the online nodes in the background.
+// Only new or changed entries are written, entries that disappeared are removed.
+// Entries of removed locations and nodes are removed right away.
+func (s *SearchService) RefreshIndex() error {
+	locations, err := location.GetLocations()
+	if err != nil {
+		return err
+	}
+
+	if err := s.pruneIndex(locations); err != nil {
+		return err
+	}
+
+	nodes, err := node.GetNodes()
+	if err != nil {
+		return err
+	}
+
+	online := make(map[string]bool)
+	for _, n := range nodes {
+		online[n.Id] = n.Status == pb.NodeStatus_ONLINE
+	}
+
+	var targets []*pb.Location
+	for _, l := range locations {
+		if online[l.NodeId] {
+			targets = append(targets, l)
+		}
+	}
+
+	return s.startRefresh(targets)
+}
+
+// RefreshLocation re-indexes a single location.
+func (s *SearchService) RefreshLocation(nodeId, locationName string) error {
+	return s.startRefresh([]*pb.Location{{NodeId: nodeId, Name: locationName}})
+}
+
+func (s *SearchService) ClearIndex() error {
+	s.mu.Lock()
+	running := s.status.Running
+	s.mu.Unlock()
+
+	if running {
+		return errors.New("index refresh is running")
+	}
+
+	return database.db.Where("1 = 1").Delete(&SearchEntry{}).Error
+}
+
+func (s *SearchService) SearchFiles(query SearchQuery) ([]*SearchEntry, error) {
+	tx := database.db.Model(&SearchEntry{})
+
+	if keyword := strings.TrimSpace(query.Keyword); keyword != "" {
+		var terms []string
+		for _, term := range strings.Fields(keyword) {
+			term = strings.ReplaceAll(term, `"`, "")
+			if term != "" {
+				terms = append(terms, `"`+term+`*"`)
+			}
+		}
+
+		if len(terms) > 0 {
+			tx = tx.Where("id IN (SELECT docid FROM search_entries_fts WHERE search_entries_fts MATCH ?)", strings.Join(terms, " "))
+		}
+	}
+
+	if query.NameGlob != "" {
+		tx = tx.Where("LOWER(name) GLOB ?", strings.ToLower(query.NameGlob))
+	}
+
+	if len(query.Extensions) > 0 {
+		extensions := make([]string, 0, len(query.Extensions))
+		for _, ext := range query.Extensions {
+			extensions = append(extensions, strings.ToLower(strings.TrimPrefix(ext, ".")))
+		}
+
+		tx = tx.Where("extension IN ?", extensions)
+	}
+
+	if query.NodeId != "" {
+		tx = tx.Where("node_id = ?", query.NodeId)
+	}
+
+	if query.Location != "" {
+		tx = tx.Where("location = ?", query.Location)
+	}
+
+	if query.Type != nil {
+		tx = tx.Where("type = ?", *query.Type)
+	}
+
+	if query.MinSize > 0 {
+		tx = tx.Where("size >= ?", query.MinSize)
+	}
+
+	if query.MaxSize > 0 {
+		tx = tx.Where("size <= ?", query.MaxSize)
+	}
+
+	if query.ModifiedAfter != nil {
+		tx = tx.Where("modified_at >= ?", *query.ModifiedAfter)
+	}
+
+	if query.ModifiedBefore != nil {
+		tx = tx.Where("modified_at <= ?", *query.ModifiedBefore)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 200
+	}
+
+	var entries []*SearchEntry
+	if err := tx.Order("modified_at desc").Limit(limit).Offset(query.Offset).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// pruneIndex removes the entries of every location that no longer exists.
+func (s *SearchService) pruneIndex(locations []*pb.Location) error {
+	var indexed []*SearchEntry
+	if err := database.db.Model(&SearchEntry{}).Distinct("node_id", "location").Find(&indexed).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range indexed {
+		if findLocation(locations, entry.NodeId, entry.Location) != nil {
+			continue
+		}
+
+		if err := database.db.Where("node_id = ? AND location = ?", entry.NodeId, entry.Location).
+			Delete(&SearchEntry{}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SearchService) startRefresh(locations []*pb.Location) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.status.Running {
+		return errors.New("index refresh is already running")
+	}
+
+	now := time.Now()
+	s.status = SearchIndexStatus{Running: true, Locations: len(locations), StartedAt: &now}
+
+	go func() {
+		for _, l := range locations {
+			if err := s.indexLocation(l.NodeId, l.Name); err != nil {
+				log.Error().Err(err).Msgf("failed to index location %s/%s", l.NodeId, l.Name)
+
+				s.mu.Lock()
+				s.status.Errors = append(s.status.Errors, fmt.Sprintf("%s/%s: %s", l.NodeId, l.Name, err))
+				s.mu.Unlock()
+			}
+		}
+
+		finishedAt := time.Now()
+		s.mu.Lock()
+		s.status.Running = false
+		s.status.FinishedAt = &finishedAt
+		s.mu.Unlock()
+	}()
+
+	return nil
+}
+
+func (s *SearchService) indexLocation(nodeId, locationName string) error {
+	var rows []*SearchEntry
+	if err := database.db.Select("id", "path", "size", "modified_at").
+		Where("node_id = ? AND location = ?", nodeId, locationName).
+		Find(&rows).Error; err != nil {
+		return err
+	}
+
+	indexed := make(map[string]searchIndexedFile, len(rows))
+	for _, row := range rows {
+		indexed[row.Path] = searchIndexedFile{id: row.ID, size: row.Size, modifiedAt: row.ModifiedAt}
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		crawlErr error
+		seen     = make(map[string]bool)
+		changed  []*SearchEntry
+		sem      = make(chan struct{}, searchConcurrency)
+		now      = time.Now()
+	)
+
+	var crawl func(dir string)
+	crawl = func(dir string) {
+		defer wg.Done()
+
+		sem <- struct{}{}
+		files, err := file.listFiles(&pb.FileContext{NodeId: nodeId, Location: locationName, Path: dir})
+		<-sem
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err != nil {
+			crawlErr = errors.Join(crawlErr, fmt.Errorf("%s: %w", dir, err))
+			return
+		}
+
+		for _, fileInfo := range files {
+			entry := &SearchEntry{
+				NodeId:    nodeId,
+				Location:  locationName,
+				Path:      path.Join(dir, fileInfo.Name),
+				Name:      fileInfo.Name,
+				Extension: strings.ToLower(strings.TrimPrefix(path.Ext(fileInfo.Name), ".")),
+				Type:      fileInfo.Type,
+				Size:      fileInfo.Size,
+				IndexedAt: now,
+			}
+
+			if fileInfo.ModifiedAt != nil {
+				entry.ModifiedAt = fileInfo.ModifiedAt.AsTime()
+			}
+
+			seen[entry.Path] = true
+			if old, ok := indexed[entry.Path]; !ok || old.size != entry.Size || !old.modifiedAt.Equal(entry.ModifiedAt) {
+				changed = append(changed, entry)
+			}
+
+			if fileInfo.Type == pb.FileType_DIR {
+				wg.Add(1)
+				go crawl(entry.Path)
+			}
+		}
+	}
+
+	wg.Add(1)
+	crawl("/")
+	wg.Wait()
+
+	for start := 0; start < len(changed); start += searchBatchSize {
+		end := min(start+searchBatchSize, len(changed))
+		if err := database.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "node_id"}, {Name: "location"}, {Name: "path"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "extension", "type", "size", "modified_at", "indexed_at"}),
+		}).Create(changed[start:end]).Error; err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	s.status.Files += len(seen)
+	s.mu.Unlock()
+
+	// keep the old entries when parts of the tree could not be listed
+	if crawlErr != nil {
+		return crawlErr
+	}
+
+	var removed []uint
+	for p, old := range indexed {
+		if !seen[p] {
+			removed = append(removed, old.id)
+		}
+	}
+
+	return database.db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(removed); start += searchBatchSize {
+			end := min(start+searchBatchSize, len(removed))
+			if err := tx.Delete(&SearchEntry{}, removed[start:end]).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+const (
+	EventSyncHealthChecked = "sync:health"
+
+	SyncIssueError   = "error"
+	SyncIssueOverdue = "overdue"
+	SyncIssueOffline = "offline"
+
+	syncHealthInterval = 5 * time.Minute
+	syncOverdueGrace   = 10 * time.Minute
+)
+
+// SyncHealthService checks every sync for errors, missed runs and offline
+// nodes, and notifies when a sync becomes unhealthy.
+type SyncHealthService struct {
+	ctx context.Context
+
+	mu     sync.Mutex
+	issues map[string][]string
+	seen   map[string]time.Time // when a sync was first checked, for syncs that never ran
+}
+
+type SyncHealthReport struct {
+	CheckedAt time.Time
+	Total     int
+	Healthy   int
+	Errors    int
+	Overdue   int
+	Offline   int
+	Syncs     []*SyncHealth
+}
+
+type SyncHealth struct {
+	SyncId       string
+	Name         string
+	Enabled      bool
+	Status       pb.SyncStatus
+	LastSyncedAt *time.Time
+	Issues       []string // error, overdue, offline
+	Detail       string
+}
+
+var syncHealth *SyncHealthService
+var onceSyncHealth sync.Once
+
+func NewSyncHealthService() *SyncHealthService {
+	if syncHealth == nil {
+		onceSyncHealth.Do(func() {
+			syncHealth = &SyncHealthService{issues: make(map[string][]string), seen: make(map[string]time.Time)}
+		})
+	}
+
+	return syncHealth
+}
+
+func (h *SyncHealthService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	h.ctx = ctx
+
+	go func() {
+		ticker := time.NewTicker(syncHealthInterval)
+		defer ticker.Stop()
+
+		for {
+			if auth.loggedIn() {
+				if _, err := h.check(true); err != nil {
+					log.Debug().Err(err).Msg("failed to check sync health")
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// GetSyncHealthReport checks every sync now.
+func (h *SyncHealthService) GetSyncHealthReport() (*SyncHealthReport, error) {
+	return h.check(false)
+}
+
+func (h *SyncHealthService) check(alert bool) (*SyncHealthReport, error) {
+	syncs, err := fileSync.GetFileSyncList()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := node.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	online := make(map[string]bool, len(nodes))
+	names := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		names[n.Id] = n.Name
+		if n.Status == pb.NodeStatus_ONLINE {
+			online[n.Id] = true
+		}
+	}
+
+	report := &SyncHealthReport{CheckedAt: time.Now(), Total: len(syncs)}
+
+	h.mu.Lock()
+	seen := make(map[string]time.Time, len(syncs))
+	for _, s := range syncs {
+		seen[s.Id] = report.CheckedAt
+		if at, ok := h.seen[s.Id]; ok {
+			seen[s.Id] = at
+		}
+	}
+	h.seen = seen
+	h.mu.Unlock()
+
+	for _, s := range syncs {
+		health := h.evaluate(s, online, names, seen[s.Id], report.CheckedAt)
+		report.Syncs = append(report.Syncs, health)
+
+		if len(health.Issues) == 0 {
+			report.Healthy++
+		}
+
+		for _, issue := range health.Issues {
+			switch issue {
+			case SyncIssueError:
+				report.Errors++
+			case SyncIssueOverdue:
+				report.Overdue++
+			case SyncIssueOffline:
+				report.Offline++
+			}
+		}
+	}
+
+	// only the checks that alert remember the issues, a report viewed in
+	// between must not swallow the notification
+	if alert {
+		h.mu.Lock()
+		var alerts []*SyncHealth
+		issues := make(map[string][]string, len(report.Syncs))
+		for _, health := range report.Syncs {
+			issues[health.SyncId] = health.Issues
+			for _, issue := range health.Issues {
+				if !slices.Contains(h.issues[health.SyncId], issue) {
+					alerts = append(alerts, health)
+					break
+				}
+			}
+		}
+		h.issues = issues
+		h.mu.Unlock()
+
+		if len(alerts) > 0 {
+			h.notify(alerts)
+		}
+	}
+
+	application.Get().EmitEvent(EventSyncHealthChecked, report)
+
+	return report, nil
+}
+
+// evaluate returns the issues of a sync. A sync that never synced is overdue
+// once it was seen for longer than a sync that fell behind.
+func (h *SyncHealthService) evaluate(s *pb.Sync, online map[string]bool, names map[string]string, seen, now time.Time) *SyncHealth {
+	health := &SyncHealth{SyncId: s.Id, Name: s.Name, Enabled: s.Enabled, Status: s.Status}
+	if s.GetConfig().GetLastSyncedAt() != nil {
+		lastSyncedAt := s.Config.LastSyncedAt.AsTime()
+		health.LastSyncedAt = &lastSyncedAt
+	}
+
+	if !s.Enabled {
+		return health
+	}
+
+	var details []string
+	if s.Status == pb.SyncStatus_ERROR {
+		health.Issues = append(health.Issues, SyncIssueError)
+		detail := "sync failed"
+		if excerpt := syncLogExcerpt(s.GetConfig().GetLog()); excerpt != "" {
+			lines := strings.Split(excerpt, "\n")
+			detail = lines[len(lines)-1]
+		}
+		details = append(details, detail)
+	}
+
+	var offline []string
+	for _, nodeId := range []string{s.SrcNodeId, s.DestNodeId} {
+		if !online[nodeId] && !slices.Contains(offline, nodeId) {
+			offline = append(offline, nodeId)
+		}
+	}
+
+	if len(offline) > 0 {
+		health.Issues = append(health.Issues, SyncIssueOffline)
+		for _, nodeId := range offline {
+			name := names[nodeId]
+			if name == "" {
+				name = nodeId
+			}
+
+			details = append(details, fmt.Sprintf("node %s is offline", name))
+		}
+	}
+
+	// paused syncs and syncs outside their window are expected to fall behind
+	interval := time.Duration(s.GetConfig().GetInterval()) * time.Second
+	if interval > 0 && s.Status != pb.SyncStatus_SYNCING && !syncPause.IsSyncPaused() && syncSchedule.InWindow(s.Id) {
+		if health.LastSyncedAt == nil {
+			if elapsed := now.Sub(seen); elapsed > 2*interval+syncOverdueGrace {
+				health.Issues = append(health.Issues, SyncIssueOverdue)
+				details = append(details, fmt.Sprintf("never synced in %s", elapsed.Round(time.Minute)))
+			}
+		} else if elapsed := now.Sub(*health.LastSyncedAt); elapsed > 2*interval+syncOverdueGrace {
+			health.Issues = append(health.Issues, SyncIssueOverdue)
+			details = append(details, fmt.Sprintf("last synced %s ago", elapsed.Round(time.Minute)))
+		}
+	}
+
+	health.Detail = strings.Join(details, "; ")
+	return health
+}
+
+func (h *SyncHealthService) notify(alerts []*SyncHealth) {
+	enabled, err := preferences.GetSyncHealthNotifications()
+	if err != nil || !enabled {
+		return
+	}
+
+	title := fmt.Sprintf("%d 个同步异常", len(alerts))
+	var lines []string
+	for _, health := range alerts {
+		lines = append(lines, fmt.Sprintf("%s: %s", health.Name, health.Detail))
+	}
+
+	if err := notify(title, strings.Join(lines, "\n")); err != nil {
+		log.Error().Err(err).Msg("failed to show sync health notification")
+	}
+}
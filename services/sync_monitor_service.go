@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+)
+
+const (
+	EventSyncStarted   = "sync:started"
+	EventSyncSucceeded = "sync:succeeded"
+	EventSyncFailed    = "sync:failed"
+
+	syncMonitorInterval = 5 * time.Second
+	syncLogExcerptLines = 20
+	syncMaxRuns         = 200
+)
+
+// SyncMonitorService polls the sync list, turns status changes into events
+// and keeps the history of sync runs.
+type SyncMonitorService struct {
+	ctx context.Context
+
+	mu     sync.Mutex
+	states map[string]*syncState
+}
+
+type SyncRun struct {
+	gorm.Model
+
+	SyncId     string `gorm:"index"`
+	Name       string
+	Status     string // syncing, success, error, stopped
+	Log        string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+type SyncEvent struct {
+	SyncId string
+	Name   string
+	Status pb.SyncStatus
+	Log    string
+	Run    *SyncRun
+}
+
+type syncState struct {
+	status       pb.SyncStatus
+	lastSyncedAt time.Time
+	run          *SyncRun
+}
+
+var syncMonitor *SyncMonitorService
+var onceSyncMonitor sync.Once
+
+func NewSyncMonitorService() *SyncMonitorService {
+	if syncMonitor == nil {
+		onceSyncMonitor.Do(func() {
+			syncMonitor = &SyncMonitorService{states: make(map[string]*syncState)}
+		})
+	}
+
+	return syncMonitor
+}
+
+func (m *SyncMonitorService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	m.ctx = ctx
+
+	go func() {
+		ticker := time.NewTicker(syncMonitorInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.refresh(); err != nil {
+					log.Debug().Err(err).Msg("failed to refresh sync status")
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (m *SyncMonitorService) GetSyncRuns(syncId string, limit int) ([]*SyncRun, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var runs []*SyncRun
+	if err := database.db.Where("sync_id = ?", syncId).Order("id desc").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+func (m *SyncMonitorService) ClearSyncRuns(syncId string) error {
+	return database.db.Unscoped().Where("sync_id = ? AND finished_at IS NOT NULL", syncId).Delete(&SyncRun{}).Error
+}
+
+func (m *SyncMonitorService) refresh() error {
+	if !auth.loggedIn() {
+		return nil
+	}
+
+	syncs, err := fileSync.GetFileSyncList()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	present := make(map[string]bool, len(syncs))
+	for _, s := range syncs {
+		present[s.Id] = true
+		if err := m.observe(s); err != nil {
+			log.Error().Err(err).Msgf("failed to record sync %s", s.Name)
+		}
+	}
+
+	for id := range m.states {
+		if !present[id] {
+			delete(m.states, id)
+		}
+	}
+
+	return nil
+}
+
+func (m *SyncMonitorService) observe(s *pb.Sync) error {
+	var lastSyncedAt time.Time
+	if s.GetConfig().GetLastSyncedAt() != nil {
+		lastSyncedAt = s.Config.LastSyncedAt.AsTime()
+	}
+
+	state, ok := m.states[s.Id]
+	if !ok {
+		// continue a run that was open when the application quit
+		var run SyncRun
+		if err := database.db.Where("sync_id = ? AND finished_at IS NULL", s.Id).Order("id desc").Limit(1).Find(&run).Error; err != nil {
+			return err
+		}
+
+		state = &syncState{status: s.Status, lastSyncedAt: lastSyncedAt}
+		if run.ID != 0 {
+			state.run = &run
+		}
+		m.states[s.Id] = state
+
+		if s.Status == pb.SyncStatus_SYNCING {
+			if state.run == nil {
+				return m.start(s, state)
+			}
+
+			return nil
+		}
+
+		if state.run != nil {
+			return m.finish(s, state, lastSyncedAt)
+		}
+
+		return nil
+	}
+
+	previous := state.status
+	synced := lastSyncedAt.After(state.lastSyncedAt)
+	state.status = s.Status
+	state.lastSyncedAt = lastSyncedAt
+
+	switch {
+	case s.Status == pb.SyncStatus_SYNCING && previous != pb.SyncStatus_SYNCING:
+		return m.start(s, state)
+	case s.Status != pb.SyncStatus_SYNCING && state.run != nil:
+		return m.finish(s, state, lastSyncedAt)
+	case s.Status == pb.SyncStatus_ERROR && previous != pb.SyncStatus_ERROR,
+		s.Status == pb.SyncStatus_SUCCESS && synced:
+		// the whole run happened between two polls
+		if err := m.start(s, state); err != nil {
+			return err
+		}
+
+		return m.finish(s, state, lastSyncedAt)
+	}
+
+	return nil
+}
+
+func (m *SyncMonitorService) start(s *pb.Sync, state *syncState) error {
+	state.run = &SyncRun{SyncId: s.Id, Name: s.Name, Status: "syncing", StartedAt: time.Now()}
+	if err := database.db.Create(state.run).Error; err != nil {
+		state.run = nil
+		return err
+	}
+
+	application.Get().EmitEvent(EventSyncStarted, &SyncEvent{SyncId: s.Id, Name: s.Name, Status: s.Status, Run: state.run})
+	return nil
+}
+
+func (m *SyncMonitorService) finish(s *pb.Sync, state *syncState, lastSyncedAt time.Time) error {
+	run := state.run
+	state.run = nil
+
+	finishedAt := time.Now()
+	if !lastSyncedAt.IsZero() && lastSyncedAt.After(run.StartedAt) {
+		finishedAt = lastSyncedAt
+	}
+
+	run.FinishedAt = &finishedAt
+	var event string
+	switch s.Status {
+	case pb.SyncStatus_SUCCESS:
+		event = EventSyncSucceeded
+		run.Status = "success"
+	case pb.SyncStatus_ERROR:
+		event = EventSyncFailed
+		run.Status = "error"
+		run.Log = syncLogExcerpt(s.GetConfig().GetLog())
+	default:
+		// stopped before it finished
+		run.Status = "stopped"
+	}
+
+	if err := database.db.Save(run).Error; err != nil {
+		return err
+	}
+
+	var ids []uint
+	if err := database.db.Model(&SyncRun{}).Where("sync_id = ?", s.Id).
+		Order("id desc").Offset(syncMaxRuns).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	if len(ids) > 0 {
+		if err := database.db.Unscoped().Delete(&SyncRun{}, ids).Error; err != nil {
+			return err
+		}
+	}
+
+	if event != "" {
+		application.Get().EmitEvent(event, &SyncEvent{SyncId: s.Id, Name: s.Name, Status: s.Status, Log: run.Log, Run: run})
+	}
+
+	return nil
+}
+
+// syncLogExcerpt returns the last lines of a sync log.
+func syncLogExcerpt(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > syncLogExcerptLines {
+		lines = lines[len(lines)-syncLogExcerptLines:]
+	}
+
+	return strings.Join(lines, "\n")
+}
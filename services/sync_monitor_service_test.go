@@ -0,0 +1,33 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSyncLogExcerpt(t *testing.T) {
+	var lines []string
+	for i := range syncLogExcerptLines + 5 {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+
+	tests := []struct {
+		name string
+		log  string
+		want string
+	}{
+		{"empty", "", ""},
+		{"trailing newlines", "a\nb\n\n", "a\nb"},
+		{"short", "a\nb", "a\nb"},
+		{"long", strings.Join(lines, "\n") + "\n", strings.Join(lines[5:], "\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := syncLogExcerpt(tt.log); got != tt.want {
+				t.Errorf("syncLogExcerpt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
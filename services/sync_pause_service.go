@@ -0,0 +1,403 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+const (
+	EventSyncPauseChanged = "sync:pause"
+
+	SyncPauseManual  = "manual"
+	SyncPauseBattery = "battery"
+	SyncPauseNetwork = "network"
+
+	syncPauseCheckInterval = 30 * time.Second
+)
+
+// SyncPauseService stops every running sync at once and starts them again
+// later. Syncs are paused automatically on battery or on a configured network
+// and resumed once that no longer applies, unless they were paused by hand.
+// Resuming by hand keeps them running until the condition changes.
+type SyncPauseService struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	resumed string // the automatic pause reason that was resumed by hand
+}
+
+type SyncPauseState struct {
+	Paused    bool
+	Reason    string // manual, battery, network
+	SyncIds   []string
+	OnBattery bool
+	Network   string
+}
+
+var syncPause *SyncPauseService
+var onceSyncPause sync.Once
+
+func NewSyncPauseService() *SyncPauseService {
+	if syncPause == nil {
+		onceSyncPause.Do(func() {
+			syncPause = &SyncPauseService{}
+		})
+	}
+
+	return syncPause
+}
+
+func (p *SyncPauseService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	p.ctx = ctx
+
+	go func() {
+		ticker := time.NewTicker(syncPauseCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := p.check(); err != nil {
+				log.Debug().Err(err).Msg("failed to check automatic sync pause")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (p *SyncPauseService) GetSyncPauseState() (*SyncPauseState, error) {
+	reason, ids, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyncPauseState{
+		Paused:    reason != "",
+		Reason:    reason,
+		SyncIds:   ids,
+		OnBattery: onBattery(),
+		Network:   currentNetwork(),
+	}, nil
+}
+
+func (p *SyncPauseService) PauseAllSyncs() error {
+	return p.pause(SyncPauseManual)
+}
+
+// ResumeAllSyncs starts the paused syncs. They are not paused automatically
+// again while the battery or network condition lasts.
+func (p *SyncPauseService) ResumeAllSyncs() error {
+	reason, err := p.condition()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.resumed = reason
+	return p.resume()
+}
+
+// IsSyncPaused reports whether syncs are paused, schedules must not start them.
+func (p *SyncPauseService) IsSyncPaused() bool {
+	reason, _, err := p.load()
+	return err == nil && reason != ""
+}
+
+func (p *SyncPauseService) pause(reason string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current, ids, err := p.load()
+	if err != nil {
+		return err
+	}
+
+	if current != "" {
+		// a manual pause is not lifted automatically
+		if reason == SyncPauseManual && current != SyncPauseManual {
+			return p.save(reason, ids)
+		}
+
+		return nil
+	}
+
+	syncs, err := fileSync.GetFileSyncList()
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	ids = []string{}
+	for _, s := range syncs {
+		// stopped by hand or by its schedule, nothing to resume later
+		if !s.Enabled || !syncSchedule.InWindow(s.Id) {
+			continue
+		}
+
+		if err := fileSync.StopFileSync(s); err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		ids = append(ids, s.Id)
+	}
+
+	return errors.Join(errs, p.save(reason, ids))
+}
+
+func (p *SyncPauseService) resume() error {
+	current, ids, err := p.load()
+	if err != nil {
+		return err
+	}
+
+	if current == "" {
+		return nil
+	}
+
+	syncs, err := fileSync.GetFileSyncList()
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	for _, s := range syncs {
+		// the schedule starts it once its window opens
+		if slices.Contains(ids, s.Id) && syncSchedule.InWindow(s.Id) {
+			errs = errors.Join(errs, fileSync.StartFileSync(s))
+		}
+	}
+
+	return errors.Join(errs, p.save("", nil))
+}
+
+// check pauses or resumes the syncs automatically.
+func (p *SyncPauseService) check() error {
+	if !auth.loggedIn() {
+		return nil
+	}
+
+	reason, err := p.condition()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	if reason != p.resumed {
+		p.resumed = ""
+	}
+	resumed := p.resumed
+	p.mu.Unlock()
+
+	if reason != "" {
+		if reason == resumed {
+			return nil
+		}
+
+		return p.pause(reason)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current, _, err := p.load()
+	if err != nil {
+		return err
+	}
+
+	if current == SyncPauseBattery || current == SyncPauseNetwork {
+		return p.resume()
+	}
+
+	return nil
+}
+
+// condition returns the reason the syncs should be paused automatically now,
+// or "".
+func (p *SyncPauseService) condition() (string, error) {
+	pauseOnBattery, err := preferences.GetPauseSyncOnBattery()
+	if err != nil {
+		return "", err
+	}
+
+	networks, err := preferences.GetPauseSyncNetworks()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case pauseOnBattery && onBattery():
+		return SyncPauseBattery, nil
+	case len(networks) > 0 && slices.Contains(networks, currentNetwork()):
+		return SyncPauseNetwork, nil
+	}
+
+	return "", nil
+}
+
+func (p *SyncPauseService) load() (string, []string, error) {
+	reason, err := localStorage.GetLocalStorage("syncPause.reason")
+	if err != nil {
+		return "", nil, err
+	}
+
+	ids, err := localStorage.GetLocalStorage("syncPause.syncIds")
+	if err != nil {
+		return "", nil, err
+	}
+
+	var result []string
+	if values, ok := ids.([]any); ok {
+		for _, value := range values {
+			if id, ok := value.(string); ok {
+				result = append(result, id)
+			}
+		}
+	}
+
+	if reason == nil {
+		return "", result, nil
+	}
+
+	return reason.(string), result, nil
+}
+
+func (p *SyncPauseService) save(reason string, ids []string) error {
+	if reason == "" {
+		if err := localStorage.DelLocalStorage("syncPause"); err != nil {
+			return err
+		}
+	} else {
+		if err := localStorage.SetLocalStorage("syncPause.reason", reason); err != nil {
+			return err
+		}
+
+		if err := localStorage.SetLocalStorage("syncPause.syncIds", ids); err != nil {
+			return err
+		}
+	}
+
+	application.Get().EmitEvent(EventSyncPauseChanged, reason)
+	return nil
+}
+
+// onBattery reports whether the machine runs on battery power.
+func onBattery() bool {
+	switch runtime.GOOS {
+	case "darwin":
+		output, err := exec.Command("pmset", "-g", "batt").Output()
+		return err == nil && bytes.Contains(output, []byte("'Battery Power'"))
+	case "linux":
+		supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+		var mains, online bool
+		for _, supply := range supplies {
+			typ, err := os.ReadFile(filepath.Join(supply, "type"))
+			if err != nil || strings.TrimSpace(string(typ)) != "Mains" {
+				continue
+			}
+
+			mains = true
+			if value, err := os.ReadFile(filepath.Join(supply, "online")); err == nil && strings.TrimSpace(string(value)) == "1" {
+				online = true
+			}
+		}
+
+		return mains && !online
+	case "windows":
+		// BatteryStatus 1 means the battery is discharging
+		output, err := exec.Command("powershell", "-NoProfile", "-Command",
+			"(Get-CimInstance Win32_Battery).BatteryStatus").Output()
+		return err == nil && strings.TrimSpace(string(output)) == "1"
+	}
+
+	return false
+}
+
+// currentNetwork returns the name of the connected Wi-Fi network, or "" on a
+// wired network.
+func currentNetwork() string {
+	var output []byte
+	var err error
+
+	switch runtime.GOOS {
+	case "darwin":
+		device := wifiDevice()
+		if device == "" {
+			return ""
+		}
+
+		output, err = exec.Command("networksetup", "-getairportnetwork", device).Output()
+		if err == nil {
+			if _, name, ok := strings.Cut(string(output), "Current Wi-Fi Network: "); ok {
+				return strings.TrimSpace(name)
+			}
+		}
+	case "linux":
+		output, err = exec.Command("nmcli", "-t", "-f", "active,ssid", "dev", "wifi").Output()
+		if err == nil {
+			scanner := bufio.NewScanner(bytes.NewReader(output))
+			for scanner.Scan() {
+				if name, ok := strings.CutPrefix(scanner.Text(), "yes:"); ok {
+					return name
+				}
+			}
+		}
+	case "windows":
+		output, err = exec.Command("netsh", "wlan", "show", "interfaces").Output()
+		if err == nil {
+			scanner := bufio.NewScanner(bytes.NewReader(output))
+			for scanner.Scan() {
+				key, value, ok := strings.Cut(scanner.Text(), ":")
+				if ok && strings.TrimSpace(key) == "SSID" {
+					return strings.TrimSpace(value)
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// wifiDevice returns the macOS Wi-Fi interface, which isn't always en0.
+func wifiDevice() string {
+	output, err := exec.Command("networksetup", "-listallhardwareports").Output()
+	if err != nil {
+		return ""
+	}
+
+	var wifi bool
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if port, ok := strings.CutPrefix(line, "Hardware Port: "); ok {
+			wifi = port == "Wi-Fi" || port == "AirPort"
+			continue
+		}
+
+		if device, ok := strings.CutPrefix(line, "Device: "); ok && wifi {
+			return strings.TrimSpace(device)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+)
+
+// SyncScheduleService limits syncs to time windows. At the window boundaries
+// the syncs are started and stopped on their nodes.
+type SyncScheduleService struct {
+	ctx context.Context
+
+	mu sync.Mutex
+}
+
+type SyncSchedule struct {
+	gorm.Model
+
+	SyncId  string `gorm:"uniqueIndex"`
+	Enabled bool
+	Days    []int  `gorm:"serializer:json"` // weekdays, 0 is sunday, empty is every day
+	Start   string // 15:04
+	End     string // 15:04, before start for a window past midnight
+	Open    *bool  // window state last applied to the nodes, nil until applied
+	Active  bool   `gorm:"-"`
+}
+
+var syncSchedule *SyncScheduleService
+var onceSyncSchedule sync.Once
+
+func NewSyncScheduleService() *SyncScheduleService {
+	if syncSchedule == nil {
+		onceSyncSchedule.Do(func() {
+			syncSchedule = &SyncScheduleService{}
+		})
+	}
+
+	return syncSchedule
+}
+
+func (s *SyncScheduleService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	s.ctx = ctx
+
+	go s.schedule(ctx)
+	return nil
+}
+
+func (s *SyncScheduleService) schedule(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if auth.loggedIn() {
+			if err := s.apply(time.Now()); err != nil {
+				log.Error().Err(err).Msg("failed to apply sync schedules")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SyncScheduleService) GetSyncSchedules() ([]*SyncSchedule, error) {
+	var schedules []*SyncSchedule
+	if err := database.db.Order("id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, schedule := range schedules {
+		schedule.Active = schedule.contains(now)
+	}
+
+	return schedules, nil
+}
+
+// GetSyncSchedule returns the schedule of the sync, or nil if it runs at any
+// time.
+func (s *SyncScheduleService) GetSyncSchedule(syncId string) (*SyncSchedule, error) {
+	var schedule SyncSchedule
+	if err := database.db.Where("sync_id = ?", syncId).Limit(1).Find(&schedule).Error; err != nil {
+		return nil, err
+	}
+
+	if schedule.ID == 0 {
+		return nil, nil
+	}
+
+	schedule.Active = schedule.contains(time.Now())
+	return &schedule, nil
+}
+
+func (s *SyncScheduleService) SaveSyncSchedule(schedule *SyncSchedule) (*SyncSchedule, error) {
+	if err := s.validate(schedule); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.GetSyncSchedule(schedule.SyncId)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		schedule.ID = existing.ID
+		schedule.CreatedAt = existing.CreatedAt
+	}
+
+	// applied again with the new window
+	schedule.Open = nil
+	if err := database.db.Save(schedule).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.apply(time.Now()); err != nil {
+		return nil, err
+	}
+
+	schedule.Active = schedule.contains(time.Now())
+	return schedule, nil
+}
+
+// RemoveSyncSchedule lets the sync run at any time again. It is not started,
+// a sync stopped outside its window stays stopped until started by hand.
+func (s *SyncScheduleService) RemoveSyncSchedule(syncId string) error {
+	return database.db.Unscoped().Where("sync_id = ?", syncId).Delete(&SyncSchedule{}).Error
+}
+
+// InWindow reports whether the sync may run now.
+func (s *SyncScheduleService) InWindow(syncId string) bool {
+	schedule, err := s.GetSyncSchedule(syncId)
+	if err != nil || schedule == nil || !schedule.Enabled {
+		return true
+	}
+
+	return schedule.Active
+}
+
+// apply starts and stops the scheduled syncs whose window opened or closed
+// since it was last applied.
+func (s *SyncScheduleService) apply(now time.Time) error {
+	syncs, err := fileSync.GetFileSyncList()
+	if err != nil {
+		return err
+	}
+
+	changes, err := s.transitions(syncs, now)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	for _, change := range changes {
+		if change.open {
+			err = fileSync.StartFileSync(change.sync)
+		} else {
+			err = fileSync.StopFileSync(change.sync)
+		}
+
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("failed to apply the window of %s: %w", change.sync.Name, err))
+
+			// retried on the next tick
+			if err := database.db.Model(change.schedule).Update("open", nil).Error; err != nil {
+				errs = errors.Join(errs, err)
+			}
+			continue
+		}
+
+		if change.open {
+			log.Info().Msgf("sync %s window opened", change.sync.Name)
+		} else {
+			log.Info().Msgf("sync %s window closed", change.sync.Name)
+		}
+	}
+
+	return errs
+}
+
+type scheduleChange struct {
+	schedule *SyncSchedule
+	sync     *pb.Sync
+	open     bool
+}
+
+// transitions returns the windows that opened or closed and records them as
+// applied, so a concurrent call doesn't apply them again.
+func (s *SyncScheduleService) transitions(syncs []*pb.Sync, now time.Time) ([]*scheduleChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var schedules []*SyncSchedule
+	if err := database.db.Where("enabled = ?", true).Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+
+	var changes []*scheduleChange
+	for _, schedule := range schedules {
+		idx := slices.IndexFunc(syncs, func(sync *pb.Sync) bool { return sync.Id == schedule.SyncId })
+		if idx < 0 || !syncs[idx].Enabled {
+			continue
+		}
+
+		open := schedule.contains(now)
+		if schedule.Open != nil && *schedule.Open == open {
+			continue
+		}
+
+		// retried on the next tick once the syncs are resumed
+		if open && syncPause.IsSyncPaused() {
+			continue
+		}
+
+		if err := database.db.Model(schedule).Update("open", open).Error; err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, &scheduleChange{schedule: schedule, sync: syncs[idx], open: open})
+	}
+
+	return changes, nil
+}
+
+func (s *SyncScheduleService) validate(schedule *SyncSchedule) error {
+	if schedule.SyncId == "" {
+		return errors.New("sync is required")
+	}
+
+	start, err := time.Parse("15:04", schedule.Start)
+	if err != nil {
+		return fmt.Errorf("invalid start time %s", schedule.Start)
+	}
+
+	end, err := time.Parse("15:04", schedule.End)
+	if err != nil {
+		return fmt.Errorf("invalid end time %s", schedule.End)
+	}
+
+	if start.Equal(end) {
+		return errors.New("start and end time must differ")
+	}
+
+	for _, day := range schedule.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("invalid weekday %d", day)
+		}
+	}
+
+	return nil
+}
+
+// contains reports whether the time falls in the window. A window past
+// midnight belongs to the day it starts on.
+func (s *SyncSchedule) contains(t time.Time) bool {
+	start, err := time.Parse("15:04", s.Start)
+	if err != nil {
+		return false
+	}
+
+	end, err := time.Parse("15:04", s.End)
+	if err != nil {
+		return false
+	}
+
+	minutes := t.Hour()*60 + t.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+
+	if startMinutes < endMinutes {
+		return s.allows(t.Weekday()) && minutes >= startMinutes && minutes < endMinutes
+	}
+
+	return (minutes >= startMinutes && s.allows(t.Weekday())) ||
+		(minutes < endMinutes && s.allows((t.Weekday()+6)%7))
+}
+
+func (s *SyncSchedule) allows(day time.Weekday) bool {
+	return len(s.Days) == 0 || slices.Contains(s.Days, int(day))
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncScheduleContains(t *testing.T) {
+	// 2024-01-01 is a monday
+	at := func(day int, clock string) time.Time {
+		value, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return time.Date(2024, time.January, day, value.Hour(), value.Minute(), 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		schedule SyncSchedule
+		time     time.Time
+		want     bool
+	}{
+		{"before window", SyncSchedule{Start: "09:00", End: "17:00"}, at(1, "08:59"), false},
+		{"window start", SyncSchedule{Start: "09:00", End: "17:00"}, at(1, "09:00"), true},
+		{"inside window", SyncSchedule{Start: "09:00", End: "17:00"}, at(1, "16:59"), true},
+		{"window end", SyncSchedule{Start: "09:00", End: "17:00"}, at(1, "17:00"), false},
+		{"other weekday", SyncSchedule{Start: "09:00", End: "17:00", Days: []int{2}}, at(1, "10:00"), false},
+		{"listed weekday", SyncSchedule{Start: "09:00", End: "17:00", Days: []int{1}}, at(1, "10:00"), true},
+		{"past midnight before", SyncSchedule{Start: "22:00", End: "06:00"}, at(1, "23:00"), true},
+		{"past midnight after", SyncSchedule{Start: "22:00", End: "06:00"}, at(2, "03:00"), true},
+		{"past midnight end", SyncSchedule{Start: "22:00", End: "06:00"}, at(2, "06:00"), false},
+		{"past midnight outside", SyncSchedule{Start: "22:00", End: "06:00"}, at(2, "12:00"), false},
+		{"past midnight start day", SyncSchedule{Start: "22:00", End: "06:00", Days: []int{1}}, at(1, "23:00"), true},
+		{"past midnight next day", SyncSchedule{Start: "22:00", End: "06:00", Days: []int{1}}, at(2, "03:00"), true},
+		{"past midnight day before", SyncSchedule{Start: "22:00", End: "06:00", Days: []int{1}}, at(1, "03:00"), false},
+		{"past midnight unlisted day", SyncSchedule{Start: "22:00", End: "06:00", Days: []int{1}}, at(2, "23:00"), false},
+		{"invalid start", SyncSchedule{Start: "9", End: "17:00"}, at(1, "10:00"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.contains(tt.time); got != tt.want {
+				t.Errorf("contains(%s) = %v, want %v", tt.time.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
@@ -2,14 +2,93 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"connectrpc.com/connect"
 	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"gorm.io/gorm"
 )
 
 type FileSyncService struct{}
 
+// SyncSetting holds the options of a sync that the server schema can't store.
+type SyncSetting struct {
+	gorm.Model
+
+	SyncId         string   `gorm:"uniqueIndex"`
+	Include        []string `gorm:"serializer:json"` // empty includes every file
+	Exclude        []string `gorm:"serializer:json"`
+	ConflictPolicy string   // newest, source, keep_both, ask, empty is not configured
+
+	// PreviewOnly is always true: the nodes' Start call can't carry the
+	// filters, so the nodes sync every file. PreviewSync marks what they match.
+	PreviewOnly bool `gorm:"-"`
+}
+
+// SyncConflict is a file of a duplex sync that changed on both sides since
+// the last sync.
+type SyncConflict struct {
+	gorm.Model
+
+	SyncId         string `gorm:"index"`
+	Path           string
+	SrcSize        int64
+	SrcModifiedAt  time.Time
+	DestSize       int64
+	DestModifiedAt time.Time
+	Status         string // pending, resolved
+	Resolution     string // src, dest, both
+	ResolvedAt     *time.Time
+}
+
+const (
+	SyncConflictNewest   = "newest"
+	SyncConflictSource   = "source"
+	SyncConflictKeepBoth = "keep_both"
+	SyncConflictAsk      = "ask"
+)
+
+// syncFilter applies gitignore-style patterns to paths relative to the sync
+// contexts: the last matching pattern wins, "!" negates a pattern, a trailing
+// "/" only matches directories and a leading "/" anchors it to the root.
+type syncFilter struct {
+	include []string
+	exclude []string
+}
+
+// partial uploads are never synced
+var syncDefaultExclude = []string{tmpPrefix + "*"}
+
+// SyncPlan is what a sync would do if it ran now.
+type SyncPlan struct {
+	SyncId         string
+	Duplex         bool
+	Actions        []*SyncPlanAction
+	Copies         int
+	Overwrites     int
+	Deletions      int
+	Conflicts      int
+	TransferSize   int64
+	Unchanged      int
+	Filtered       int  // actions on files the preview filters exclude
+	FiltersPreview bool // the filters only apply to this preview, see SyncSetting
+}
+
+type SyncPlanAction struct {
+	Action    string // copy, overwrite, delete, keep_both, conflict
+	Direction string // src_to_dest, dest_to_src
+	Path      string // relative to the sync contexts
+	Size      int64
+	Reason    string
+	Filtered  bool // excluded by the local filters, the nodes still sync it
+}
+
 var fileSync *FileSyncService
 var onceFileSync sync.Once
 
@@ -50,6 +129,403 @@ func (fs *FileSyncService) AddFileSync(sync *pb.Sync) (*pb.Sync, error) {
 	return response.Msg.GetSync(), err
 }
 
+// UpdateFileSync changes the name and config of a sync. CreateSync with the id
+// of an existing sync updates it, a running sync is stopped around the update
+// and started again.
+func (fs *FileSyncService) UpdateFileSync(update *pb.Sync) (*pb.Sync, error) {
+	if err := fs.validateSync(update); err != nil {
+		return nil, err
+	}
+
+	current, err := fs.getSync(update.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := &pb.Sync{
+		Id:          current.Id,
+		SrcNodeId:   current.SrcNodeId,
+		DestNodeId:  current.DestNodeId,
+		Name:        update.Name,
+		Enabled:     current.Enabled,
+		Status:      current.Status,
+		SrcContext:  current.SrcContext,
+		DestContext: current.DestContext,
+		Config: &pb.SyncConfig{
+			Interval:     update.Config.Interval,
+			Duplex:       update.Config.Duplex,
+			Limit:        update.Config.Limit,
+			Log:          current.GetConfig().GetLog(),
+			LastSyncedAt: current.GetConfig().GetLastSyncedAt(),
+		},
+	}
+
+	// paused syncs and syncs outside their window stay stopped
+	running := current.Enabled && !syncPause.IsSyncPaused() && syncSchedule.InWindow(current.Id)
+	if running {
+		if err := fs.StopFileSync(current); err != nil {
+			return nil, err
+		}
+	}
+
+	saved, err := fs.AddFileSync(updated)
+	if err != nil {
+		if running {
+			return nil, errors.Join(err, fs.StartFileSync(current))
+		}
+
+		return nil, err
+	}
+
+	if running {
+		if err := fs.StartFileSync(saved); err != nil {
+			return saved, err
+		}
+	}
+
+	return saved, nil
+}
+
+// PreviewSync compares both sides of the sync without changing anything. A
+// one-way sync copies new and changed files to the destination and deletes
+// what only exists there. A duplex sync copies new files both ways and handles
+// changed files according to the conflict policy, it never deletes.
+func (fs *FileSyncService) PreviewSync(sync *pb.Sync) (*SyncPlan, error) {
+	if sync.SrcContext == nil || sync.DestContext == nil {
+		return nil, errors.New("sync contexts are required")
+	}
+
+	setting, err := fs.GetSyncSetting(sync.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison, err := file.compare(sync.SrcContext, sync.DestContext, false)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := setting.filter()
+	plan := &SyncPlan{
+		SyncId:         sync.Id,
+		Duplex:         sync.GetConfig().GetDuplex(),
+		Unchanged:      comparison.Unchanged,
+		FiltersPreview: true,
+	}
+	add := func(action, direction string, diff *FileDiff, size int64) {
+		entry := diff.Src
+		if entry == nil {
+			entry = diff.Dest
+		}
+
+		filtered := !filter.matchPath(diff.Path, entry.Type == pb.FileType_DIR)
+		plan.Actions = append(plan.Actions, &SyncPlanAction{
+			Action:    action,
+			Direction: direction,
+			Path:      diff.Path,
+			Size:      size,
+			Reason:    diff.Reason,
+			Filtered:  filtered,
+		})
+
+		if filtered {
+			plan.Filtered++
+		}
+
+		switch action {
+		case "copy":
+			plan.Copies++
+		case "overwrite":
+			plan.Overwrites++
+		case "delete":
+			plan.Deletions++
+		case "keep_both", "conflict":
+			plan.Conflicts++
+		}
+
+		if action != "delete" && action != "conflict" {
+			plan.TransferSize += size
+		}
+	}
+
+	for _, added := range comparison.Added {
+		diffs, err := fs.expandDiff(sync.SrcContext, added, added.Src)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, diff := range diffs {
+			add("copy", "src_to_dest", diff, fileSize(diff.Src))
+		}
+	}
+
+	for _, removed := range comparison.Removed {
+		diffs, err := fs.expandDiff(sync.DestContext, removed, removed.Dest)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, diff := range diffs {
+			if plan.Duplex {
+				add("copy", "dest_to_src", diff, fileSize(diff.Dest))
+			} else {
+				add("delete", "src_to_dest", diff, fileSize(diff.Dest))
+			}
+		}
+	}
+
+	for _, diff := range comparison.Changed {
+		if !plan.Duplex {
+			add("overwrite", "src_to_dest", diff, diff.Src.Size)
+			continue
+		}
+
+		resolution := "src"
+		if fs.isConflict(sync, diff) {
+			diff.Reason = "conflict"
+			resolution = setting.resolution(diff)
+		} else if diff.Dest.ModifiedAt.AsTime().After(diff.Src.ModifiedAt.AsTime()) {
+			resolution = "dest"
+		}
+
+		switch resolution {
+		case "dest":
+			add("overwrite", "dest_to_src", diff, diff.Dest.Size)
+		case "both":
+			add("keep_both", "src_to_dest", diff, diff.Src.Size+diff.Dest.Size)
+		case "":
+			add("conflict", "", diff, 0)
+		default:
+			add("overwrite", "src_to_dest", diff, diff.Src.Size)
+		}
+	}
+
+	return plan, nil
+}
+
+// expandDiff returns a diff for every file below an added or removed
+// directory, compare only reports the directory. An empty directory stays a
+// single entry.
+func (fs *FileSyncService) expandDiff(ctx *pb.FileContext, diff *FileDiff, fileInfo *pb.File) ([]*FileDiff, error) {
+	if fileInfo.Type != pb.FileType_DIR {
+		return []*FileDiff{diff}, nil
+	}
+
+	var diffs []*FileDiff
+	err := file.walkFiles(&pb.FileContext{
+		NodeId:   ctx.NodeId,
+		Location: ctx.Location,
+		Path:     path.Join(ctx.Path, diff.Path),
+	}, func(relPath string, child *pb.File) error {
+		if child.Type == pb.FileType_DIR {
+			return nil
+		}
+
+		expanded := &FileDiff{Path: path.Join(diff.Path, relPath)}
+		if diff.Src != nil {
+			expanded.Src = child
+		} else {
+			expanded.Dest = child
+		}
+
+		diffs = append(diffs, expanded)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(diffs) == 0 {
+		return []*FileDiff{diff}, nil
+	}
+
+	return diffs, nil
+}
+
+// fileSize is the size a file adds to a transfer, directories have none.
+func fileSize(fileInfo *pb.File) int64 {
+	if fileInfo.Type == pb.FileType_DIR {
+		return 0
+	}
+
+	return fileInfo.Size
+}
+
+func (fs *FileSyncService) SaveSyncConflictPolicy(syncId, policy string) (*SyncSetting, error) {
+	switch policy {
+	case "", SyncConflictNewest, SyncConflictSource, SyncConflictKeepBoth, SyncConflictAsk:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy: %s", policy)
+	}
+
+	setting, err := fs.GetSyncSetting(syncId)
+	if err != nil {
+		return nil, err
+	}
+
+	setting.ConflictPolicy = policy
+	if err := database.db.Save(setting).Error; err != nil {
+		return nil, err
+	}
+
+	return setting, nil
+}
+
+// GetSyncConflicts returns the conflicts of the sync, only the unresolved ones
+// if pending is set.
+func (fs *FileSyncService) GetSyncConflicts(syncId string, pending bool) ([]*SyncConflict, error) {
+	tx := database.db.Where("sync_id = ?", syncId)
+	if pending {
+		tx = tx.Where("status = ?", "pending")
+	}
+
+	var conflicts []*SyncConflict
+	if err := tx.Order("id desc").Find(&conflicts).Error; err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+// DetectSyncConflicts compares both sides of a duplex sync, records the
+// conflicts and resolves them according to its policy. Conflicts stay pending
+// under the ask policy or when no policy was configured. It walks both trees,
+// so it only runs when asked for.
+func (fs *FileSyncService) DetectSyncConflicts(sync *pb.Sync) ([]*SyncConflict, error) {
+	if !sync.GetConfig().GetDuplex() {
+		return nil, nil
+	}
+
+	setting, err := fs.GetSyncSetting(sync.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison, err := file.compare(sync.SrcContext, sync.DestContext, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []*SyncConflict
+	var errs error
+	for _, diff := range comparison.Changed {
+		if !fs.isConflict(sync, diff) {
+			continue
+		}
+
+		conflict := SyncConflict{SyncId: sync.Id, Path: diff.Path, Status: "pending"}
+		if err := database.db.Where(&conflict).Limit(1).Find(&conflict).Error; err != nil {
+			return nil, err
+		}
+
+		conflict.SrcSize = diff.Src.Size
+		conflict.SrcModifiedAt = diff.Src.ModifiedAt.AsTime()
+		conflict.DestSize = diff.Dest.Size
+		conflict.DestModifiedAt = diff.Dest.ModifiedAt.AsTime()
+		if err := database.db.Save(&conflict).Error; err != nil {
+			return nil, err
+		}
+
+		if resolution := setting.resolution(diff); resolution != "" {
+			errs = errors.Join(errs, fs.resolveConflict(sync, &conflict, resolution))
+		}
+
+		conflicts = append(conflicts, &conflict)
+	}
+
+	return conflicts, errs
+}
+
+// ResolveSyncConflict keeps the src or dest version, or both with the dest
+// version renamed to a conflict copy.
+func (fs *FileSyncService) ResolveSyncConflict(id uint, resolution string) error {
+	var conflict SyncConflict
+	if err := database.db.First(&conflict, id).Error; err != nil {
+		return err
+	}
+
+	if conflict.Status != "pending" {
+		return errors.New("conflict is already resolved")
+	}
+
+	sync, err := fs.getSync(conflict.SyncId)
+	if err != nil {
+		return err
+	}
+
+	return fs.resolveConflict(sync, &conflict, resolution)
+}
+
+func (fs *FileSyncService) resolveConflict(sync *pb.Sync, conflict *SyncConflict, resolution string) error {
+	src := &pb.FileContext{
+		NodeId:   sync.SrcContext.NodeId,
+		Location: sync.SrcContext.Location,
+		Path:     path.Join(sync.SrcContext.Path, conflict.Path),
+	}
+	dest := &pb.FileContext{
+		NodeId:   sync.DestContext.NodeId,
+		Location: sync.DestContext.Location,
+		Path:     path.Join(sync.DestContext.Path, conflict.Path),
+	}
+
+	switch resolution {
+	case "src":
+		if err := file.copyFile(src, file.cloneContext(dest)); err != nil {
+			return err
+		}
+	case "dest":
+		if err := file.copyFile(dest, file.cloneContext(src)); err != nil {
+			return err
+		}
+	case "both":
+		// the next sync copies the conflict copy to the src side
+		ext := path.Ext(dest.Path)
+		_, err := rpc.FileSystemService.Move(
+			context.Background(),
+			connect.NewRequest(&pb.FileMoveRequest{
+				Src: dest,
+				Dest: &pb.FileContext{
+					NodeId:   dest.NodeId,
+					Location: dest.Location,
+					Path:     strings.TrimSuffix(dest.Path, ext) + ".conflict-" + time.Now().Format("20060102-150405") + ext,
+				},
+			}),
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := file.copyFile(src, file.cloneContext(dest)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown conflict resolution: %s", resolution)
+	}
+
+	now := time.Now()
+	conflict.Status = "resolved"
+	conflict.Resolution = resolution
+	conflict.ResolvedAt = &now
+	return database.db.Save(conflict).Error
+}
+
+// isConflict reports whether both sides changed since the last sync. A sync
+// that never ran has nothing to compare with, only a file that is a directory
+// on the other side conflicts then.
+func (fs *FileSyncService) isConflict(sync *pb.Sync, diff *FileDiff) bool {
+	if diff.Reason == "type" {
+		return true
+	}
+
+	lastSyncedAt := sync.GetConfig().GetLastSyncedAt()
+	if lastSyncedAt == nil {
+		return false
+	}
+
+	return diff.Src.ModifiedAt.AsTime().After(lastSyncedAt.AsTime()) &&
+		diff.Dest.ModifiedAt.AsTime().After(lastSyncedAt.AsTime())
+}
+
 func (fs *FileSyncService) RemoveFileSync(syncId string) error {
 	_, err := rpc.SyncService.RemoveSync(
 		context.Background(),
@@ -57,15 +533,73 @@ func (fs *FileSyncService) RemoveFileSync(syncId string) error {
 			SyncId: syncId,
 		}),
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("sync_id = ?", syncId).Delete(&SyncConflict{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("sync_id = ?", syncId).Delete(&SyncSchedule{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("sync_id = ?", syncId).Delete(&SyncRun{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Where("sync_id = ?", syncId).Delete(&SyncSetting{}).Error
+	})
 }
 
+// GetSyncSetting returns the local settings of the sync, or empty settings if
+// none were saved.
+func (fs *FileSyncService) GetSyncSetting(syncId string) (*SyncSetting, error) {
+	setting := SyncSetting{SyncId: syncId, PreviewOnly: true}
+	if err := database.db.Where("sync_id = ?", syncId).Limit(1).Find(&setting).Error; err != nil {
+		return nil, err
+	}
+
+	return &setting, nil
+}
+
+// SaveSyncPreviewFilters stores the include and exclude patterns of the sync.
+// They are preview-only: the nodes sync every file, PreviewSync marks the
+// actions the patterns would exclude.
+func (fs *FileSyncService) SaveSyncPreviewFilters(syncId string, include, exclude []string) (*SyncSetting, error) {
+	setting, err := fs.GetSyncSetting(syncId)
+	if err != nil {
+		return nil, err
+	}
+
+	setting.Include = include
+	setting.Exclude = exclude
+	if err := database.db.Save(setting).Error; err != nil {
+		return nil, err
+	}
+
+	return setting, nil
+}
+
+// StartFileSync starts the sync on its nodes. Pending conflicts found by
+// DetectSyncConflicts prevent the start until they are resolved.
 func (fs *FileSyncService) StartFileSync(sync *pb.Sync) error {
 	if !sync.Enabled {
 		return nil
 	}
 
+	var pending int64
+	if err := database.db.Model(&SyncConflict{}).Where("sync_id = ? AND status = ?", sync.Id, "pending").
+		Count(&pending).Error; err != nil {
+		return err
+	}
+
+	if pending > 0 {
+		return fmt.Errorf("%d conflicts of %s must be resolved first", pending, sync.Name)
+	}
+
 	if err := fs.startSync(sync.Id, sync.SrcNodeId); err != nil {
 		return err
 	}
@@ -112,3 +646,118 @@ func (fs *FileSyncService) stopSync(syncId, nodeId string) error {
 
 	return err
 }
+
+func (fs *FileSyncService) getSync(syncId string) (*pb.Sync, error) {
+	syncs, err := fs.GetFileSyncList()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sync := range syncs {
+		if sync.Id == syncId {
+			return sync, nil
+		}
+	}
+
+	return nil, fmt.Errorf("sync %s not found", syncId)
+}
+
+func (fs *FileSyncService) validateSync(sync *pb.Sync) error {
+	if sync.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if sync.Config == nil {
+		return errors.New("config is required")
+	}
+
+	if sync.Config.Interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+
+	if sync.Config.Limit < 0 {
+		return errors.New("limit must not be negative")
+	}
+
+	return nil
+}
+
+func (s *SyncSetting) filter() *syncFilter {
+	return &syncFilter{
+		include: s.Include,
+		exclude: append(slices.Clone(syncDefaultExclude), s.Exclude...),
+	}
+}
+
+// matchPath is match for an entry below the root, it is excluded with any of
+// its directories.
+func (f *syncFilter) matchPath(relPath string, isDir bool) bool {
+	for i := range len(relPath) {
+		if relPath[i] == '/' && !f.match(relPath[:i], true) {
+			return false
+		}
+	}
+
+	return f.match(relPath, isDir)
+}
+
+// match reports whether the entry takes part in the sync. Directories are only
+// checked against the exclude patterns.
+func (f *syncFilter) match(relPath string, isDir bool) bool {
+	if f.matches(f.exclude, relPath, isDir) {
+		return false
+	}
+
+	return isDir || len(f.include) == 0 || f.matches(f.include, relPath, isDir)
+}
+
+func (f *syncFilter) matches(patterns []string, relPath string, isDir bool) bool {
+	var matched bool
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		if strings.HasSuffix(pattern, "/") {
+			if !isDir {
+				continue
+			}
+
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+
+		// an anchored name only matches at the root
+		if strings.HasPrefix(pattern, "/") && !strings.Contains(pattern[1:], "/") && strings.Contains(relPath, "/") {
+			continue
+		}
+
+		if matchGlob(pattern, relPath) {
+			matched = !negate
+		}
+	}
+
+	return matched
+}
+
+// resolution returns how the policy resolves a conflict, "" leaves it to the user.
+func (s *SyncSetting) resolution(diff *FileDiff) string {
+	switch s.ConflictPolicy {
+	case SyncConflictSource:
+		return "src"
+	case SyncConflictKeepBoth:
+		return "both"
+	case SyncConflictNewest:
+		if diff.Dest.ModifiedAt.AsTime().After(diff.Src.ModifiedAt.AsTime()) {
+			return "dest"
+		}
+
+		return "src"
+	}
+
+	// ask, or no policy was chosen
+	return ""
+}
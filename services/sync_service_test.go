@@ -0,0 +1,68 @@
+package services
+
+import "testing"
+
+func TestSyncFilterMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"no patterns", nil, nil, "a/b.txt", false, true},
+		{"excluded extension", nil, []string{"*.log"}, "a.log", false, false},
+		{"excluded nested extension", nil, []string{"*.log"}, "dir/a.log", false, false},
+		{"not excluded", nil, []string{"*.log"}, "a.txt", false, true},
+		{"negated exclude", nil, []string{"*.log", "!keep.log"}, "keep.log", false, true},
+		{"last pattern wins", nil, []string{"!keep.log", "*.log"}, "keep.log", false, false},
+		{"directory pattern", nil, []string{"build/"}, "build", true, false},
+		{"directory pattern on file", nil, []string{"build/"}, "build", false, true},
+		{"anchored at root", nil, []string{"/tmp"}, "tmp", true, false},
+		{"anchored below root", nil, []string{"/tmp"}, "a/tmp", true, true},
+		{"comment", nil, []string{"# *.txt"}, "a.txt", false, true},
+		{"included", []string{"*.go"}, nil, "a.go", false, true},
+		{"not included", []string{"*.go"}, nil, "a.txt", false, false},
+		{"directories ignore include", []string{"*.go"}, nil, "src", true, true},
+		{"exclude before include", []string{"*.go"}, []string{"*_test.go"}, "a_test.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &syncFilter{include: tt.include, exclude: tt.exclude}
+			if got := filter.match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncFilterMatchPath(t *testing.T) {
+	filter := (&SyncSetting{Include: []string{"*.go"}, Exclude: []string{"vendor/", "/build"}}).filter()
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"main.go", false, true},
+		{"cmd/app/main.go", false, true},
+		{"cmd/app", true, true},
+		{"README.md", false, false},
+		{"vendor/lib/lib.go", false, false},
+		{"vendor", true, false},
+		{"cmd/vendor/lib.go", false, false},
+		{"build/main.go", false, false},
+		{"cmd/build/main.go", false, true},
+		{tmpPrefix + "main.go", false, false},
+		{"cmd/" + tmpPrefix + "x/main.go", false, false},
+		{"cmd/" + tmpPrefix + "x", true, false},
+	}
+
+	for _, tt := range tests {
+		if got := filter.matchPath(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("matchPath(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+)
+
+// SyncTemplateService creates the same sync for every subdirectory of a
+// remote directory. Template fields may contain the placeholders {name}, the
+// subdirectory name, {path}, its full path, and {parent}, the directory it
+// is in.
+type SyncTemplateService struct {
+	ctx context.Context
+}
+
+type SyncTemplate struct {
+	gorm.Model
+
+	Name         string // name of the created syncs, e.g. "{name} backup"
+	SrcPath      string // empty is {path}
+	DestNodeId   string
+	DestLocation string
+	DestPath     string // e.g. /backup/{name}
+	Interval     int64
+	Duplex       bool
+	Limit        int64
+	Enabled      bool
+}
+
+type SyncTemplateResult struct {
+	Directory string
+	Sync      *pb.Sync
+	Skipped   bool // a sync with the same name exists
+	Error     string
+}
+
+var syncTemplatePlaceholders = []string{"{name}", "{path}", "{parent}"}
+
+var syncTemplate *SyncTemplateService
+var onceSyncTemplate sync.Once
+
+func NewSyncTemplateService() *SyncTemplateService {
+	if syncTemplate == nil {
+		onceSyncTemplate.Do(func() {
+			syncTemplate = &SyncTemplateService{}
+		})
+	}
+
+	return syncTemplate
+}
+
+func (t *SyncTemplateService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	t.ctx = ctx
+	return nil
+}
+
+func (t *SyncTemplateService) GetSyncTemplates() ([]*SyncTemplate, error) {
+	var templates []*SyncTemplate
+	if err := database.db.Order("id").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (t *SyncTemplateService) SaveSyncTemplate(template *SyncTemplate) (*SyncTemplate, error) {
+	if template.Name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	if template.DestNodeId == "" || template.DestLocation == "" || template.DestPath == "" {
+		return nil, errors.New("destination is required")
+	}
+
+	if template.Interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+
+	if template.Limit < 0 {
+		return nil, errors.New("limit must not be negative")
+	}
+
+	if err := template.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := database.db.Save(template).Error; err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+func (t *SyncTemplateService) RemoveSyncTemplate(id uint) error {
+	return database.db.Unscoped().Delete(&SyncTemplate{}, id).Error
+}
+
+// PreviewSyncTemplate returns the syncs the template would create below root
+// without creating them. Like CreateSyncsFromTemplate it reports each
+// directory, an invalid sync doesn't hide the others.
+func (t *SyncTemplateService) PreviewSyncTemplate(id uint, root *pb.FileContext) ([]*SyncTemplateResult, error) {
+	_, syncs, err := t.expand(id, root)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := t.syncNames()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SyncTemplateResult, 0, len(syncs))
+	for _, sync := range syncs {
+		result := &SyncTemplateResult{Directory: sync.SrcContext.Path, Sync: sync}
+		results = append(results, result)
+
+		if names[sync.Name] {
+			result.Skipped = true
+			continue
+		}
+
+		if err := fileSync.validateSync(sync); err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		names[sync.Name] = true
+	}
+
+	return results, nil
+}
+
+// CreateSyncsFromTemplate creates a sync for every subdirectory of root and
+// starts the enabled ones. A failed sync doesn't stop the others, the result
+// reports each directory.
+func (t *SyncTemplateService) CreateSyncsFromTemplate(id uint, root *pb.FileContext) ([]*SyncTemplateResult, error) {
+	_, syncs, err := t.expand(id, root)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := t.syncNames()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SyncTemplateResult, 0, len(syncs))
+	for _, sync := range syncs {
+		result := &SyncTemplateResult{Directory: sync.SrcContext.Path, Sync: sync}
+		results = append(results, result)
+
+		if names[sync.Name] {
+			result.Skipped = true
+			continue
+		}
+
+		if err := fileSync.validateSync(sync); err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		created, err := fileSync.AddFileSync(sync)
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		result.Sync = created
+		names[sync.Name] = true
+
+		if err := fileSync.StartFileSync(created); err != nil {
+			result.Error = fmt.Sprintf("created but failed to start: %v", err)
+		}
+	}
+
+	return results, nil
+}
+
+func (t *SyncTemplateService) syncNames() (map[string]bool, error) {
+	existing, err := fileSync.GetFileSyncList()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(existing))
+	for _, sync := range existing {
+		names[sync.Name] = true
+	}
+
+	return names, nil
+}
+
+// expand fills in the template for every subdirectory of root.
+func (t *SyncTemplateService) expand(id uint, root *pb.FileContext) (*SyncTemplate, []*pb.Sync, error) {
+	var template SyncTemplate
+	if err := database.db.First(&template, id).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := template.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	files, err := file.listFiles(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srcPath := template.SrcPath
+	if srcPath == "" {
+		srcPath = "{path}"
+	}
+
+	var syncs []*pb.Sync
+	for _, fileInfo := range files {
+		if fileInfo.Type != pb.FileType_DIR || strings.HasPrefix(fileInfo.Name, tmpPrefix) {
+			continue
+		}
+
+		replacer := strings.NewReplacer(
+			"{name}", fileInfo.Name,
+			"{path}", path.Join(root.Path, fileInfo.Name),
+			"{parent}", root.Path,
+		)
+
+		syncs = append(syncs, &pb.Sync{
+			SrcNodeId:  root.NodeId,
+			DestNodeId: template.DestNodeId,
+			Name:       replacer.Replace(template.Name),
+			Enabled:    template.Enabled,
+			SrcContext: &pb.FileContext{
+				NodeId:   root.NodeId,
+				Location: root.Location,
+				Path:     path.Clean(replacer.Replace(srcPath)),
+			},
+			DestContext: &pb.FileContext{
+				NodeId:   template.DestNodeId,
+				Location: template.DestLocation,
+				Path:     path.Clean(replacer.Replace(template.DestPath)),
+			},
+			Config: &pb.SyncConfig{
+				Interval: template.Interval,
+				Duplex:   template.Duplex,
+				Limit:    template.Limit,
+			},
+		})
+	}
+
+	return &template, syncs, nil
+}
+
+// validate checks the placeholders of the template fields. The expanded
+// values are not checked, a directory name may contain braces.
+func (s *SyncTemplate) validate() error {
+	for _, value := range []string{s.Name, s.SrcPath, s.DestPath} {
+		rest := value
+		for {
+			idx := strings.Index(rest, "{")
+			if idx < 0 {
+				break
+			}
+
+			rest = rest[idx:]
+			placeholder := rest
+			if end := strings.Index(rest, "}"); end >= 0 {
+				placeholder = rest[:end+1]
+			}
+
+			if !slices.Contains(syncTemplatePlaceholders, placeholder) {
+				return fmt.Errorf("unknown placeholder %s in %s", placeholder, value)
+			}
+
+			rest = rest[len(placeholder):]
+		}
+	}
+
+	return nil
+}
@@ -89,6 +89,40 @@ func (s *SystemService) OpenFile(filePath string) error {
 	return cmd.Start()
 }
 
+// notify shows a desktop notification.
+func notify(title, message string) error {
+	var cmd *exec.Cmd
+
+	switch rt.GOOS {
+	case "windows":
+		quote := func(value string) string { return "'" + strings.ReplaceAll(value, "'", "''") + "'" }
+		script := "Add-Type -AssemblyName System.Windows.Forms;" +
+			"$icon = New-Object System.Windows.Forms.NotifyIcon;" +
+			"$icon.Icon = [System.Drawing.SystemIcons]::Information;" +
+			"$icon.Visible = $true;" +
+			"$icon.ShowBalloonTip(10000, " + quote(title) + ", " + quote(message) + ", 'Info');" +
+			"Start-Sleep -Seconds 10; $icon.Dispose()"
+		cmd = exec.Command("powershell", "-NoProfile", "-Command", script)
+	case "darwin":
+		quote := func(value string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+		}
+		cmd = exec.Command("osascript", "-e", "display notification "+quote(message)+" with title "+quote(title))
+	case "linux":
+		cmd = exec.Command("notify-send", "-a", "PixelFS", title, message)
+	default:
+		return fmt.Errorf("unsupported platform")
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// the windows script keeps the icon up for a while, don't wait for it
+	go cmd.Wait()
+	return nil
+}
+
 func (s *SystemService) SelectDirectoryDialog(title string) (string, error) {
 	dialog := application.OpenFileDialog()
 	dialog.SetOptions(&application.OpenFileDialogOptions{
@@ -0,0 +1,616 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"gopkg.in/yaml.v3"
+)
+
+const topologyVersion = 1
+
+// TopologyService exports locations, storages, storage links and syncs to a
+// JSON or YAML document and recreates them from it, a .yaml or .yml file is
+// YAML and any other file JSON. Nodes are referenced by name, so a document
+// still applies after the nodes were registered again.
+type TopologyService struct{}
+
+type Topology struct {
+	Version      int                    `json:"version" yaml:"version"`
+	ExportedAt   time.Time              `json:"exported_at" yaml:"exported_at"`
+	Locations    []*TopologyLocation    `json:"locations" yaml:"locations"`
+	Storages     []*TopologyStorage     `json:"storages" yaml:"storages"`
+	StorageLinks []*TopologyStorageLink `json:"storage_links" yaml:"storage_links"`
+	Syncs        []*TopologySync        `json:"syncs" yaml:"syncs"`
+	Skipped      []*TopologySkipped     `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+type TopologyLocation struct {
+	Node          string          `json:"node" yaml:"node"`
+	Name          string          `json:"name" yaml:"name"`
+	Type          pb.LocationType `json:"type" yaml:"type"`
+	Path          string          `json:"path" yaml:"path"`
+	BlockSize     int64           `json:"block_size" yaml:"block_size"`
+	BlockDuration int64           `json:"block_duration" yaml:"block_duration"`
+}
+
+type TopologyStorage struct {
+	Name    string            `json:"name" yaml:"name"`
+	Network pb.StorageNetwork `json:"network" yaml:"network"`
+	S3      *TopologyS3       `json:"s3" yaml:"s3"`
+}
+
+// TopologyS3 has the keys of the JSON encoding of pb.StorageS3Config in both
+// formats.
+type TopologyS3 struct {
+	Endpoint  string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	AccessKey string `json:"access_key,omitempty" yaml:"access_key,omitempty"`
+	SecretKey string `json:"secret_key,omitempty" yaml:"secret_key,omitempty"`
+	Region    string `json:"region,omitempty" yaml:"region,omitempty"`
+	Bucket    string `json:"bucket,omitempty" yaml:"bucket,omitempty"`
+	Prefix    string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	PathStyle bool   `json:"path_style,omitempty" yaml:"path_style,omitempty"`
+}
+
+type TopologyStorageLink struct {
+	Storage   string `json:"storage" yaml:"storage"`
+	Node      string `json:"node" yaml:"node"`
+	Location  string `json:"location" yaml:"location"`
+	LimitSize int64  `json:"limit_size" yaml:"limit_size"`
+}
+
+type TopologySync struct {
+	Name     string           `json:"name" yaml:"name"`
+	Enabled  bool             `json:"enabled" yaml:"enabled"`
+	Src      *TopologyContext `json:"src" yaml:"src"`
+	Dest     *TopologyContext `json:"dest" yaml:"dest"`
+	Interval int64            `json:"interval" yaml:"interval"`
+	Duplex   bool             `json:"duplex" yaml:"duplex"`
+	Limit    int64            `json:"limit" yaml:"limit"`
+}
+
+type TopologyContext struct {
+	Node     string `json:"node" yaml:"node"`
+	Location string `json:"location" yaml:"location"`
+	Path     string `json:"path" yaml:"path"`
+}
+
+// TopologySkipped is an entry the export could not describe, it is listed so
+// the document doesn't look complete when it isn't.
+type TopologySkipped struct {
+	Kind   string `json:"kind" yaml:"kind"`
+	Name   string `json:"name" yaml:"name"`
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+type TopologyImport struct {
+	Actions []*TopologyImportAction
+	Created int
+	Failed  int
+}
+
+type TopologyImportAction struct {
+	Kind   string // location, storage, storage_link, sync
+	Name   string
+	Action string // create, exists, skip, created, failed
+	Reason string
+
+	create func() error
+}
+
+// topologyState holds the existing entries a document is planned against.
+type topologyState struct {
+	nodes        []*pb.Node
+	locations    []*pb.Location
+	storages     []*pb.Storage
+	storageLinks []*pb.StorageLink
+	syncs        []*pb.Sync
+}
+
+var topology *TopologyService
+var onceTopology sync.Once
+
+func NewTopologyService() *TopologyService {
+	if topology == nil {
+		onceTopology.Do(func() {
+			topology = &TopologyService{}
+		})
+	}
+
+	return topology
+}
+
+// ExportTopology writes the document to filePath in the format of its
+// extension. With redactSecrets the
+// secret keys of the storages are left out, they must be filled in before the
+// storages can be imported. Storages other than S3 and entries of unknown
+// nodes are listed as skipped.
+func (t *TopologyService) ExportTopology(filePath string, redactSecrets bool) error {
+	nodes, err := node.GetNodes()
+	if err != nil {
+		return err
+	}
+
+	nodeNames := make(map[string]string, len(nodes))
+	for _, n := range nodes {
+		nodeNames[n.Id] = n.Name
+	}
+
+	locations, err := location.GetLocations()
+	if err != nil {
+		return err
+	}
+
+	storages, err := storage.GetStorages()
+	if err != nil {
+		return err
+	}
+
+	storageLinks, err := storage.GetStorageLinks()
+	if err != nil {
+		return err
+	}
+
+	syncs, err := fileSync.GetFileSyncList()
+	if err != nil {
+		return err
+	}
+
+	doc := &Topology{Version: topologyVersion, ExportedAt: time.Now()}
+
+	skip := func(kind, name, reason string) {
+		doc.Skipped = append(doc.Skipped, &TopologySkipped{Kind: kind, Name: name, Reason: reason})
+	}
+
+	locationNames := make(map[string]string, len(locations))
+	for _, loc := range locations {
+		locationNames[loc.Id] = loc.Name
+		if nodeNames[loc.NodeId] == "" {
+			skip("location", loc.Name, fmt.Sprintf("node %s not found", loc.NodeId))
+			continue
+		}
+
+		doc.Locations = append(doc.Locations, &TopologyLocation{
+			Node:          nodeNames[loc.NodeId],
+			Name:          loc.Name,
+			Type:          loc.Type,
+			Path:          loc.Path,
+			BlockSize:     loc.BlockSize,
+			BlockDuration: loc.BlockDuration,
+		})
+	}
+
+	storageNames := make(map[string]string, len(storages))
+	for _, s := range storages {
+		storageNames[s.Id] = s.Name
+
+		s3 := s.GetS3()
+		if s3 == nil {
+			skip("storage", s.Name, fmt.Sprintf("unsupported storage type %s", s.Type))
+			continue
+		}
+
+		config := &TopologyS3{
+			Endpoint:  s3.Endpoint,
+			AccessKey: s3.AccessKey,
+			SecretKey: s3.SecretKey,
+			Region:    s3.Region,
+			Bucket:    s3.Bucket,
+			Prefix:    s3.Prefix,
+			PathStyle: s3.PathStyle,
+		}
+		if redactSecrets {
+			config.SecretKey = ""
+		}
+
+		doc.Storages = append(doc.Storages, &TopologyStorage{Name: s.Name, Network: s.Network, S3: config})
+	}
+
+	for _, link := range storageLinks {
+		name := storageNames[link.StorageId] + " -> " + nodeNames[link.NodeId] + ":" + locationNames[link.LocationId]
+		switch {
+		case storageNames[link.StorageId] == "":
+			skip("storage_link", name, fmt.Sprintf("storage %s not found", link.StorageId))
+			continue
+		case nodeNames[link.NodeId] == "":
+			skip("storage_link", name, fmt.Sprintf("node %s not found", link.NodeId))
+			continue
+		case locationNames[link.LocationId] == "":
+			skip("storage_link", name, fmt.Sprintf("location %s not found", link.LocationId))
+			continue
+		}
+
+		doc.StorageLinks = append(doc.StorageLinks, &TopologyStorageLink{
+			Storage:   storageNames[link.StorageId],
+			Node:      nodeNames[link.NodeId],
+			Location:  locationNames[link.LocationId],
+			LimitSize: link.LimitSize,
+		})
+	}
+
+	for _, s := range syncs {
+		if nodeId := s.SrcContext.GetNodeId(); nodeNames[nodeId] == "" {
+			skip("sync", s.Name, fmt.Sprintf("node %s not found", nodeId))
+			continue
+		}
+
+		if nodeId := s.DestContext.GetNodeId(); nodeNames[nodeId] == "" {
+			skip("sync", s.Name, fmt.Sprintf("node %s not found", nodeId))
+			continue
+		}
+
+		doc.Syncs = append(doc.Syncs, &TopologySync{
+			Name:     s.Name,
+			Enabled:  s.Enabled,
+			Src:      &TopologyContext{Node: nodeNames[s.SrcContext.GetNodeId()], Location: s.SrcContext.GetLocation(), Path: s.SrcContext.GetPath()},
+			Dest:     &TopologyContext{Node: nodeNames[s.DestContext.GetNodeId()], Location: s.DestContext.GetLocation(), Path: s.DestContext.GetPath()},
+			Interval: s.GetConfig().GetInterval(),
+			Duplex:   s.GetConfig().GetDuplex(),
+			Limit:    s.GetConfig().GetLimit(),
+		})
+	}
+
+	return t.write(filePath, doc)
+}
+
+func (t *TopologyService) write(filePath string, doc *Topology) error {
+	var data []byte
+	var err error
+	if isYamlFile(filePath) {
+		data, err = yaml.Marshal(doc)
+	} else {
+		data, err = json.MarshalIndent(doc, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	// the document may hold secret keys
+	return os.WriteFile(filePath, data, 0600)
+}
+
+// PreviewTopologyImport returns what ImportTopology would do without changing
+// anything.
+func (t *TopologyService) PreviewTopologyImport(filePath string) (*TopologyImport, error) {
+	doc, err := t.load(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.apply(doc, false)
+}
+
+// ImportTopology creates the entries of the document that don't exist yet.
+// Entries are matched by name, importing a document twice creates nothing the
+// second time.
+func (t *TopologyService) ImportTopology(filePath string) (*TopologyImport, error) {
+	doc, err := t.load(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.apply(doc, true)
+}
+
+func (t *TopologyService) load(filePath string) (*Topology, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc Topology
+	if isYamlFile(filePath) {
+		err = yaml.Unmarshal(data, &doc)
+	} else {
+		err = json.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Version <= 0 || doc.Version > topologyVersion {
+		return nil, fmt.Errorf("unsupported document version %d", doc.Version)
+	}
+
+	return &doc, nil
+}
+
+// apply plans the document and creates the planned entries. The storage
+// links and syncs refer to the ids of the created locations and storages, so
+// they are planned again once those exist.
+func (t *TopologyService) apply(doc *Topology, create bool) (*TopologyImport, error) {
+	state, err := t.state()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TopologyImport{Actions: planTopology(doc, state)}
+	if !create {
+		return result, nil
+	}
+
+	for _, action := range result.Actions {
+		if action.Kind == "location" || action.Kind == "storage" {
+			result.run(action)
+		}
+	}
+
+	if state, err = t.state(); err != nil {
+		return nil, err
+	}
+
+	// the plan has one action per entry in the same order every time
+	for i, action := range planTopology(doc, state) {
+		if action.Kind == "storage_link" || action.Kind == "sync" {
+			result.Actions[i] = action
+			result.run(action)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *TopologyImport) run(action *TopologyImportAction) {
+	if action.Action != "create" {
+		return
+	}
+
+	if err := action.create(); err != nil {
+		action.Action = "failed"
+		action.Reason = err.Error()
+		r.Failed++
+		return
+	}
+
+	action.Action = "created"
+	r.Created++
+}
+
+func (t *TopologyService) state() (*topologyState, error) {
+	nodes, err := node.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := location.GetLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	storages, err := storage.GetStorages()
+	if err != nil {
+		return nil, err
+	}
+
+	storageLinks, err := storage.GetStorageLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	syncs, err := fileSync.GetFileSyncList()
+	if err != nil {
+		return nil, err
+	}
+
+	return &topologyState{
+		nodes:        nodes,
+		locations:    locations,
+		storages:     storages,
+		storageLinks: storageLinks,
+		syncs:        syncs,
+	}, nil
+}
+
+// planTopology returns an action for every entry of the document, in the
+// order of the document. Entries a preview would create may be referred to by
+// later entries.
+func planTopology(doc *Topology, state *topologyState) []*TopologyImportAction {
+	var actions []*TopologyImportAction
+	planned := make(map[string]bool)
+
+	for _, skipped := range doc.Skipped {
+		actions = append(actions, &TopologyImportAction{
+			Kind:   skipped.Kind,
+			Name:   skipped.Name,
+			Action: "skip",
+			Reason: "not exported: " + skipped.Reason,
+		})
+	}
+
+	for _, loc := range doc.Locations {
+		action := &TopologyImportAction{Kind: "location", Name: loc.Node + ":" + loc.Name, Action: "create"}
+		nodeId, err := resolveNode(state.nodes, loc.Node)
+		switch {
+		case err != nil:
+			action.Action = "skip"
+			action.Reason = err.Error()
+		case findLocation(state.locations, nodeId, loc.Name) != nil:
+			action.Action = "exists"
+		default:
+			planned["location:"+nodeId+"/"+loc.Name] = true
+		}
+
+		action.create = func() error {
+			_, err := location.addLocation(&pb.Location{
+				NodeId:        nodeId,
+				Name:          loc.Name,
+				Type:          loc.Type,
+				Path:          loc.Path,
+				BlockSize:     loc.BlockSize,
+				BlockDuration: loc.BlockDuration,
+			})
+			return err
+		}
+		actions = append(actions, action)
+	}
+
+	for _, s := range doc.Storages {
+		action := &TopologyImportAction{Kind: "storage", Name: s.Name, Action: "create"}
+		switch {
+		case slices.ContainsFunc(state.storages, func(existing *pb.Storage) bool { return existing.Name == s.Name }):
+			action.Action = "exists"
+		case s.S3 == nil:
+			action.Action = "skip"
+			action.Reason = "unsupported storage type"
+		case s.S3.SecretKey == "":
+			action.Action = "skip"
+			action.Reason = "secret key is redacted"
+		default:
+			planned["storage:"+s.Name] = true
+		}
+
+		action.create = func() error {
+			_, err := storage.AddS3Storage(s.Name, &pb.StorageS3Config{
+				Endpoint:  s.S3.Endpoint,
+				AccessKey: s.S3.AccessKey,
+				SecretKey: s.S3.SecretKey,
+				Region:    s.S3.Region,
+				Bucket:    s.S3.Bucket,
+				Prefix:    s.S3.Prefix,
+				PathStyle: s.S3.PathStyle,
+			}, s.Network)
+			return err
+		}
+		actions = append(actions, action)
+	}
+
+	for _, link := range doc.StorageLinks {
+		action := &TopologyImportAction{
+			Kind:   "storage_link",
+			Name:   link.Storage + " -> " + link.Node + ":" + link.Location,
+			Action: "create",
+		}
+
+		nodeId, nodeErr := resolveNode(state.nodes, link.Node)
+		idx := slices.IndexFunc(state.storages, func(existing *pb.Storage) bool { return existing.Name == link.Storage })
+		loc := findLocation(state.locations, nodeId, link.Location)
+
+		switch {
+		case nodeErr != nil:
+			action.Action = "skip"
+			action.Reason = nodeErr.Error()
+		case idx < 0 && !planned["storage:"+link.Storage]:
+			action.Action = "skip"
+			action.Reason = fmt.Sprintf("storage %s not found", link.Storage)
+		case loc == nil && !planned["location:"+nodeId+"/"+link.Location]:
+			action.Action = "skip"
+			action.Reason = fmt.Sprintf("location %s not found", link.Location)
+		case idx >= 0 && loc != nil && slices.ContainsFunc(state.storageLinks, func(existing *pb.StorageLink) bool {
+			return existing.StorageId == state.storages[idx].Id && existing.NodeId == nodeId && existing.LocationId == loc.Id
+		}):
+			action.Action = "exists"
+		}
+
+		action.create = func() error {
+			if idx < 0 || loc == nil {
+				return errors.New("storage or location was not created")
+			}
+
+			_, err := storage.AddStorageLink(&pb.StorageLink{
+				StorageId:  state.storages[idx].Id,
+				NodeId:     nodeId,
+				LocationId: loc.Id,
+				LimitSize:  link.LimitSize,
+			})
+			return err
+		}
+		actions = append(actions, action)
+	}
+
+	for _, s := range doc.Syncs {
+		action := &TopologyImportAction{Kind: "sync", Name: s.Name, Action: "create"}
+		if s.Src == nil || s.Dest == nil {
+			action.Action = "skip"
+			action.Reason = "source and destination are required"
+			actions = append(actions, action)
+			continue
+		}
+
+		srcNodeId, srcErr := resolveNode(state.nodes, s.Src.Node)
+		destNodeId, destErr := resolveNode(state.nodes, s.Dest.Node)
+		sync := &pb.Sync{
+			SrcNodeId:   srcNodeId,
+			DestNodeId:  destNodeId,
+			Name:        s.Name,
+			Enabled:     s.Enabled,
+			SrcContext:  &pb.FileContext{NodeId: srcNodeId, Location: s.Src.Location, Path: s.Src.Path},
+			DestContext: &pb.FileContext{NodeId: destNodeId, Location: s.Dest.Location, Path: s.Dest.Path},
+			Config:      &pb.SyncConfig{Interval: s.Interval, Duplex: s.Duplex, Limit: s.Limit},
+		}
+
+		switch {
+		case slices.ContainsFunc(state.syncs, func(existing *pb.Sync) bool { return existing.Name == s.Name }):
+			action.Action = "exists"
+		case srcErr != nil:
+			action.Action = "skip"
+			action.Reason = srcErr.Error()
+		case destErr != nil:
+			action.Action = "skip"
+			action.Reason = destErr.Error()
+		default:
+			if err := fileSync.validateSync(sync); err != nil {
+				action.Action = "skip"
+				action.Reason = err.Error()
+			}
+		}
+
+		action.create = func() error {
+			created, err := fileSync.AddFileSync(sync)
+			if err != nil {
+				return err
+			}
+
+			if err := fileSync.StartFileSync(created); err != nil {
+				return fmt.Errorf("created but failed to start: %w", err)
+			}
+
+			return nil
+		}
+		actions = append(actions, action)
+	}
+
+	return actions
+}
+
+// resolveNode returns the id of the node with the name. Names are not unique,
+// an online node is preferred and a name that still matches several nodes is
+// refused.
+func resolveNode(nodes []*pb.Node, name string) (string, error) {
+	var matches, online []string
+	for _, n := range nodes {
+		if n.Name != name {
+			continue
+		}
+
+		matches = append(matches, n.Id)
+		if n.Status == pb.NodeStatus_ONLINE {
+			online = append(online, n.Id)
+		}
+	}
+
+	switch {
+	case len(online) == 1:
+		return online[0], nil
+	case len(online) > 1:
+		return "", fmt.Errorf("%d online nodes are named %s", len(online), name)
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) > 1:
+		return "", fmt.Errorf("%d offline nodes are named %s", len(matches), name)
+	}
+
+	return "", fmt.Errorf("node %s not found", name)
+}
+
+func isYamlFile(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".yaml" || ext == ".yml"
+}
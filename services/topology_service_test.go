@@ -0,0 +1,181 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+)
+
+func TestResolveNode(t *testing.T) {
+	nodes := []*pb.Node{
+		{Id: "n1", Name: "home", Status: pb.NodeStatus_ONLINE},
+		{Id: "n2", Name: "home", Status: pb.NodeStatus_OFFLINE},
+		{Id: "n3", Name: "nas", Status: pb.NodeStatus_OFFLINE},
+		{Id: "n4", Name: "nas", Status: pb.NodeStatus_OFFLINE},
+		{Id: "n5", Name: "office", Status: pb.NodeStatus_OFFLINE},
+		{Id: "n6", Name: "laptop", Status: pb.NodeStatus_ONLINE},
+		{Id: "n7", Name: "laptop", Status: pb.NodeStatus_ONLINE},
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"home", "n1", false},
+		{"office", "n5", false},
+		{"nas", "", true},
+		{"laptop", "", true},
+		{"missing", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveNode(nodes, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveNode() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("resolveNode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanTopology(t *testing.T) {
+	state := &topologyState{
+		nodes: []*pb.Node{
+			{Id: "n1", Name: "home", Status: pb.NodeStatus_ONLINE},
+			{Id: "n2", Name: "office", Status: pb.NodeStatus_OFFLINE},
+			{Id: "n3", Name: "nas", Status: pb.NodeStatus_OFFLINE},
+			{Id: "n4", Name: "nas", Status: pb.NodeStatus_OFFLINE},
+		},
+		locations:    []*pb.Location{{Id: "l1", NodeId: "n1", Name: "media"}},
+		storages:     []*pb.Storage{{Id: "s1", Name: "s3a"}},
+		storageLinks: []*pb.StorageLink{{Id: "k1", StorageId: "s1", NodeId: "n1", LocationId: "l1"}},
+		syncs:        []*pb.Sync{{Id: "y1", Name: "photos"}},
+	}
+
+	context := func(node string) *TopologyContext {
+		return &TopologyContext{Node: node, Location: "media", Path: "/"}
+	}
+
+	doc := &Topology{
+		Version: topologyVersion,
+		Skipped: []*TopologySkipped{{Kind: "storage", Name: "ftp", Reason: "unsupported storage type"}},
+		Locations: []*TopologyLocation{
+			{Node: "home", Name: "media"},
+			{Node: "office", Name: "docs"},
+			{Node: "missing", Name: "docs"},
+			{Node: "nas", Name: "docs"},
+		},
+		Storages: []*TopologyStorage{
+			{Name: "s3a", S3: &TopologyS3{SecretKey: "secret"}},
+			{Name: "s3b", S3: &TopologyS3{SecretKey: "secret"}},
+			{Name: "s3c", S3: &TopologyS3{}},
+			{Name: "s3d"},
+		},
+		StorageLinks: []*TopologyStorageLink{
+			{Storage: "s3b", Node: "office", Location: "docs"},
+			{Storage: "s3a", Node: "home", Location: "media"},
+			{Storage: "s3z", Node: "home", Location: "media"},
+			{Storage: "s3a", Node: "home", Location: "nope"},
+			{Storage: "s3c", Node: "home", Location: "media"},
+		},
+		Syncs: []*TopologySync{
+			{Name: "photos", Src: context("home"), Dest: context("office"), Interval: 60},
+			{Name: "music", Src: context("home"), Dest: context("office"), Interval: 60},
+			{Name: "no dest", Src: context("home")},
+			{Name: "ambiguous", Src: context("nas"), Dest: context("office"), Interval: 60},
+			{Name: "no interval", Src: context("home"), Dest: context("office")},
+		},
+	}
+
+	want := []struct {
+		kind   string
+		name   string
+		action string
+	}{
+		{"storage", "ftp", "skip"},
+		{"location", "home:media", "exists"},
+		{"location", "office:docs", "create"},
+		{"location", "missing:docs", "skip"},
+		{"location", "nas:docs", "skip"},
+		{"storage", "s3a", "exists"},
+		{"storage", "s3b", "create"},
+		{"storage", "s3c", "skip"},
+		{"storage", "s3d", "skip"},
+		{"storage_link", "s3b -> office:docs", "create"},
+		{"storage_link", "s3a -> home:media", "exists"},
+		{"storage_link", "s3z -> home:media", "skip"},
+		{"storage_link", "s3a -> home:nope", "skip"},
+		{"storage_link", "s3c -> home:media", "skip"},
+		{"sync", "photos", "exists"},
+		{"sync", "music", "create"},
+		{"sync", "no dest", "skip"},
+		{"sync", "ambiguous", "skip"},
+		{"sync", "no interval", "skip"},
+	}
+
+	actions := planTopology(doc, state)
+	if len(actions) != len(want) {
+		t.Fatalf("planTopology() returned %d actions, want %d", len(actions), len(want))
+	}
+
+	for i, action := range actions {
+		if action.Kind != want[i].kind || action.Name != want[i].name || action.Action != want[i].action {
+			t.Errorf("action %d = %s %s %s (%s), want %s %s %s",
+				i, action.Kind, action.Name, action.Action, action.Reason, want[i].kind, want[i].name, want[i].action)
+		}
+	}
+}
+
+func TestTopologyDocumentFormats(t *testing.T) {
+	doc := &Topology{
+		Version:    topologyVersion,
+		ExportedAt: time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC),
+		Locations:  []*TopologyLocation{{Node: "home", Name: "media", Path: "/data/媒体", BlockSize: 4 << 20}},
+		Storages:   []*TopologyStorage{{Name: "s3a", S3: &TopologyS3{Endpoint: "https://s3.example.com", SecretKey: "secret", PathStyle: true}}},
+		StorageLinks: []*TopologyStorageLink{
+			{Storage: "s3a", Node: "home", Location: "media", LimitSize: 1 << 30},
+		},
+		Syncs: []*TopologySync{
+			{Name: "photos", Enabled: true, Src: &TopologyContext{Node: "home", Location: "media", Path: "/"}, Dest: &TopologyContext{Node: "nas", Location: "backup", Path: "/photos"}, Interval: 60},
+		},
+		Skipped: []*TopologySkipped{{Kind: "storage", Name: "ftp", Reason: "unsupported storage type"}},
+	}
+
+	for _, name := range []string{"topology.json", "topology.yaml", "topology.YML"} {
+		t.Run(name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), name)
+			if err := (&TopologyService{}).write(filePath, doc); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := (&TopologyService{}).load(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, doc) {
+				t.Errorf("load() = %+v, want %+v", got, doc)
+			}
+		})
+	}
+
+	t.Run("unsupported version", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "topology.yaml")
+		if err := os.WriteFile(filePath, []byte("version: 2\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := (&TopologyService{}).load(filePath); err == nil {
+			t.Error("load() accepted version 2")
+		}
+	})
+}
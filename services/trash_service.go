@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+)
+
+// trashDirName is created at the root of every location that had items deleted
+// through the desktop, it is hidden from the file list.
+const trashDirName = ".pixelfstrash"
+
+type TrashService struct {
+	ctx context.Context
+}
+
+type TrashItem struct {
+	gorm.Model
+
+	NodeId       string `gorm:"index"`
+	Location     string `gorm:"index"`
+	OriginalPath string
+	TrashPath    string
+	Name         string
+	Type         pb.FileType
+	Size         int64
+	TrashedAt    time.Time `gorm:"index"`
+}
+
+var trash *TrashService
+var onceTrash sync.Once
+
+func NewTrashService() *TrashService {
+	if trash == nil {
+		onceTrash.Do(func() {
+			trash = &TrashService{}
+		})
+	}
+
+	return trash
+}
+
+func (t *TrashService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	t.ctx = ctx
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			if err := t.purgeTrash(); err != nil {
+				log.Error().Err(err).Msg("failed to purge trash")
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (i *TrashItem) originalContext() *pb.FileContext {
+	return &pb.FileContext{NodeId: i.NodeId, Location: i.Location, Path: i.OriginalPath}
+}
+
+func (i *TrashItem) trashContext() *pb.FileContext {
+	return &pb.FileContext{NodeId: i.NodeId, Location: i.Location, Path: i.TrashPath}
+}
+
+// ListTrash returns the trashed items, empty node id or location match all.
+func (t *TrashService) ListTrash(nodeId, location string) ([]*TrashItem, error) {
+	tx := database.db.Model(&TrashItem{})
+	if nodeId != "" {
+		tx = tx.Where("node_id = ?", nodeId)
+	}
+
+	if location != "" {
+		tx = tx.Where("location = ?", location)
+	}
+
+	var items []*TrashItem
+	if err := tx.Order("trashed_at desc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func (t *TrashService) RestoreFromTrash(id uint) error {
+	var item TrashItem
+	if err := database.db.First(&item, id).Error; err != nil {
+		return err
+	}
+
+	if _, err := file.StatFile(item.originalContext()); err == nil {
+		return fmt.Errorf("%s already exists", item.OriginalPath)
+	}
+
+	if err := file.mkdirAll(&pb.FileContext{
+		NodeId:   item.NodeId,
+		Location: item.Location,
+		Path:     path.Dir(item.OriginalPath),
+	}); err != nil {
+		return err
+	}
+
+	_, err := rpc.FileSystemService.Move(
+		context.Background(),
+		connect.NewRequest(&pb.FileMoveRequest{
+			Src:  item.trashContext(),
+			Dest: item.originalContext(),
+		}),
+	)
+	if err != nil {
+		return err
+	}
+
+	return database.db.Unscoped().Delete(&item).Error
+}
+
+func (t *TrashService) DeleteFromTrash(id uint) error {
+	var item TrashItem
+	if err := database.db.First(&item, id).Error; err != nil {
+		return err
+	}
+
+	return t.deleteItem(&item)
+}
+
+// EmptyTrash permanently removes the trashed items, empty node id or location match all.
+func (t *TrashService) EmptyTrash(nodeId, location string) error {
+	items, err := t.ListTrash(nodeId, location)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	for _, item := range items {
+		errs = errors.Join(errs, t.deleteItem(item))
+	}
+
+	return errs
+}
+
+func (t *TrashService) deleteItem(item *TrashItem) error {
+	if err := file.removeFile(item.trashContext()); err != nil {
+		// already gone on the node, only forget about it
+		if _, statErr := file.StatFile(item.trashContext()); statErr == nil {
+			return err
+		}
+	}
+
+	return database.db.Unscoped().Delete(item).Error
+}
+
+func (t *TrashService) purgeTrash() error {
+	days, err := preferences.GetTrashRetentionDays()
+	if err != nil {
+		return err
+	}
+
+	if days <= 0 {
+		return nil
+	}
+
+	var items []*TrashItem
+	if err := database.db.Where("trashed_at < ?", time.Now().AddDate(0, 0, -days)).Find(&items).Error; err != nil {
+		return err
+	}
+
+	var errs error
+	for _, item := range items {
+		errs = errors.Join(errs, t.deleteItem(item))
+	}
+
+	return errs
+}
+
+// trashFile moves the file into the trash directory of its location.
+func (t *TrashService) trashFile(ctx *pb.FileContext) (*TrashItem, error) {
+	// the trash is inside the location, the root can't be moved into it
+	if path.Clean("/"+ctx.Path) == "/" {
+		return nil, errors.New("the root of a location can't be removed")
+	}
+
+	stat, err := file.StatFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trashDir := &pb.FileContext{NodeId: ctx.NodeId, Location: ctx.Location, Path: "/" + trashDirName}
+	if err := file.mkdirAll(trashDir); err != nil {
+		return nil, err
+	}
+
+	_, name := path.Split(path.Clean(ctx.Path))
+	item := TrashItem{
+		NodeId:       ctx.NodeId,
+		Location:     ctx.Location,
+		OriginalPath: ctx.Path,
+		TrashPath:    path.Join(trashDir.Path, fmt.Sprintf("%d-%s", time.Now().UnixNano(), name)),
+		Name:         name,
+		Type:         stat.Type,
+		Size:         stat.Size,
+		TrashedAt:    time.Now(),
+	}
+
+	_, err = rpc.FileSystemService.Move(
+		context.Background(),
+		connect.NewRequest(&pb.FileMoveRequest{
+			Src:  ctx,
+			Dest: item.trashContext(),
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.db.Create(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+func isInTrash(p string) bool {
+	p = path.Clean("/" + p)
+	return p == "/"+trashDirName || strings.HasPrefix(p, "/"+trashDirName+"/")
+}
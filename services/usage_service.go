@@ -0,0 +1,425 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+)
+
+const (
+	EventUsageScanFinished = "usage:finished"
+
+	// only the largest files of every scan are kept
+	usageTopFiles = 100
+)
+
+// UsageService scans the disk usage of a remote directory and keeps the
+// results as snapshots, so they can be compared over time.
+type UsageService struct {
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+type UsageSnapshot struct {
+	gorm.Model
+
+	NodeId     string `gorm:"index"`
+	Location   string `gorm:"index"`
+	Path       string
+	Status     string // running, success, failed
+	Error      string
+	TotalSize  int64
+	Files      int
+	Dirs       int
+	FinishedAt *time.Time
+}
+
+// UsageEntry is a directory or one of the largest files of a snapshot, Path is
+// relative to the scanned directory and the root is "".
+type UsageEntry struct {
+	ID         uint   `gorm:"primarykey"`
+	SnapshotId uint   `gorm:"index"`
+	Parent     string `gorm:"index"`
+	Path       string
+	Name       string
+	IsDir      bool
+	Size       int64
+	Files      int
+	ModifiedAt time.Time
+}
+
+// UsageBucket sums the files of a snapshot by extension (Kind "type") or by
+// the age of their last modification (Kind "age").
+type UsageBucket struct {
+	ID         uint   `gorm:"primarykey"`
+	SnapshotId uint   `gorm:"index"`
+	Kind       string // type, age
+	Name       string
+	Size       int64
+	Files      int
+}
+
+type UsageDiff struct {
+	Path    string
+	OldSize int64
+	NewSize int64
+	Delta   int64
+}
+
+var usageAgeBuckets = []struct {
+	name string
+	age  time.Duration
+}{
+	{"day", 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+	{"month", 30 * 24 * time.Hour},
+	{"year", 365 * 24 * time.Hour},
+}
+
+var usage *UsageService
+var onceUsage sync.Once
+
+func NewUsageService() *UsageService {
+	if usage == nil {
+		onceUsage.Do(func() {
+			usage = &UsageService{running: make(map[string]bool)}
+		})
+	}
+
+	return usage
+}
+
+func (u *UsageService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	// scans that were in progress when the application quit
+	return database.db.Model(&UsageSnapshot{}).Where("status = ?", "running").
+		Updates(map[string]any{"status": "failed", "error": "interrupted", "finished_at": time.Now()}).Error
+}
+
+// ScanUsage scans the directory in the background, the finished snapshot is
+// sent with the usage:finished event.
+func (u *UsageService) ScanUsage(ctx *pb.FileContext) (*UsageSnapshot, error) {
+	key := ctx.NodeId + ":" + ctx.Location + ":" + path.Clean("/"+ctx.Path)
+
+	u.mu.Lock()
+	if u.running[key] {
+		u.mu.Unlock()
+		return nil, errors.New("usage scan is running")
+	}
+	u.running[key] = true
+	u.mu.Unlock()
+
+	snapshot := UsageSnapshot{
+		NodeId:   ctx.NodeId,
+		Location: ctx.Location,
+		Path:     ctx.Path,
+		Status:   "running",
+	}
+
+	if err := database.db.Create(&snapshot).Error; err != nil {
+		u.mu.Lock()
+		delete(u.running, key)
+		u.mu.Unlock()
+		return nil, err
+	}
+
+	// the scan keeps updating its snapshot
+	result := snapshot
+
+	go func() {
+		defer func() {
+			u.mu.Lock()
+			delete(u.running, key)
+			u.mu.Unlock()
+		}()
+
+		snapshot.Status = "success"
+		if err := u.scan(ctx, &snapshot); err != nil {
+			snapshot.Status = "failed"
+			snapshot.Error = err.Error()
+		}
+
+		now := time.Now()
+		snapshot.FinishedAt = &now
+
+		database.db.Save(&snapshot)
+		application.Get().EmitEvent(EventUsageScanFinished, &snapshot)
+	}()
+
+	return &result, nil
+}
+
+// GetUsageSnapshots returns the snapshots, empty node id or location match all.
+func (u *UsageService) GetUsageSnapshots(nodeId, location string) ([]*UsageSnapshot, error) {
+	tx := database.db.Model(&UsageSnapshot{})
+	if nodeId != "" {
+		tx = tx.Where("node_id = ?", nodeId)
+	}
+
+	if location != "" {
+		tx = tx.Where("location = ?", location)
+	}
+
+	var snapshots []*UsageSnapshot
+	if err := tx.Order("id desc").Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// GetUsageTree returns the subdirectories and the largest files directly below
+// the relative path, largest first.
+func (u *UsageService) GetUsageTree(snapshotId uint, relPath string) ([]*UsageEntry, error) {
+	var entries []*UsageEntry
+	err := database.db.Where("snapshot_id = ? AND parent = ? AND path != ''", snapshotId, u.cleanPath(relPath)).
+		Order("size desc").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (u *UsageService) GetUsageBuckets(snapshotId uint, kind string) ([]*UsageBucket, error) {
+	var buckets []*UsageBucket
+	if err := database.db.Where("snapshot_id = ? AND kind = ?", snapshotId, kind).Order("size desc").Find(&buckets).Error; err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+func (u *UsageService) GetTopFiles(snapshotId uint, limit int) ([]*UsageEntry, error) {
+	return u.topEntries(snapshotId, false, limit)
+}
+
+func (u *UsageService) GetTopDirectories(snapshotId uint, limit int) ([]*UsageEntry, error) {
+	return u.topEntries(snapshotId, true, limit)
+}
+
+// CompareUsageSnapshots returns the directories whose size changed between two
+// snapshots of the same directory, largest change first.
+func (u *UsageService) CompareUsageSnapshots(oldId, newId uint) ([]*UsageDiff, error) {
+	var oldSnapshot, newSnapshot UsageSnapshot
+	if err := database.db.First(&oldSnapshot, oldId).Error; err != nil {
+		return nil, err
+	}
+
+	if err := database.db.First(&newSnapshot, newId).Error; err != nil {
+		return nil, err
+	}
+
+	if oldSnapshot.NodeId != newSnapshot.NodeId || oldSnapshot.Location != newSnapshot.Location ||
+		path.Clean("/"+oldSnapshot.Path) != path.Clean("/"+newSnapshot.Path) {
+		return nil, errors.New("snapshots are not of the same directory")
+	}
+
+	sizes := func(id uint) (map[string]int64, error) {
+		var entries []*UsageEntry
+		if err := database.db.Where("snapshot_id = ? AND is_dir = ?", id, true).Find(&entries).Error; err != nil {
+			return nil, err
+		}
+
+		result := make(map[string]int64, len(entries))
+		for _, entry := range entries {
+			result[entry.Path] = entry.Size
+		}
+
+		return result, nil
+	}
+
+	oldSizes, err := sizes(oldId)
+	if err != nil {
+		return nil, err
+	}
+
+	newSizes, err := sizes(newId)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []*UsageDiff
+	for p, size := range newSizes {
+		if size != oldSizes[p] {
+			diffs = append(diffs, &UsageDiff{Path: p, OldSize: oldSizes[p], NewSize: size, Delta: size - oldSizes[p]})
+		}
+	}
+
+	for p, size := range oldSizes {
+		if _, ok := newSizes[p]; !ok {
+			diffs = append(diffs, &UsageDiff{Path: p, OldSize: size, Delta: -size})
+		}
+	}
+
+	magnitude := func(n int64) int64 {
+		if n < 0 {
+			return -n
+		}
+
+		return n
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return magnitude(diffs[i].Delta) > magnitude(diffs[j].Delta)
+	})
+
+	return diffs, nil
+}
+
+func (u *UsageService) RemoveUsageSnapshot(id uint) error {
+	return database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("snapshot_id = ?", id).Delete(&UsageEntry{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("snapshot_id = ?", id).Delete(&UsageBucket{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&UsageSnapshot{}, id).Error
+	})
+}
+
+func (u *UsageService) topEntries(snapshotId uint, isDir bool, limit int) ([]*UsageEntry, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var entries []*UsageEntry
+	err := database.db.Where("snapshot_id = ? AND is_dir = ? AND path != ''", snapshotId, isDir).
+		Order("size desc").Limit(limit).Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (u *UsageService) scan(ctx *pb.FileContext, snapshot *UsageSnapshot) error {
+	dirs := map[string]*UsageEntry{"": {SnapshotId: snapshot.ID, IsDir: true, Name: path.Base(path.Clean("/" + ctx.Path))}}
+	buckets := make(map[string]*UsageBucket)
+	var files []*UsageEntry
+	now := time.Now()
+
+	addBucket := func(kind, name string, size int64) {
+		bucket, ok := buckets[kind+":"+name]
+		if !ok {
+			bucket = &UsageBucket{SnapshotId: snapshot.ID, Kind: kind, Name: name}
+			buckets[kind+":"+name] = bucket
+		}
+
+		bucket.Size += size
+		bucket.Files++
+	}
+
+	err := file.walkFiles(ctx, func(relPath string, fileInfo *pb.File) error {
+		parent := path.Dir(relPath)
+		if parent == "." {
+			parent = ""
+		}
+
+		if fileInfo.Type == pb.FileType_DIR {
+			dirs[relPath] = &UsageEntry{
+				SnapshotId: snapshot.ID,
+				Parent:     parent,
+				Path:       relPath,
+				Name:       fileInfo.Name,
+				IsDir:      true,
+				ModifiedAt: fileInfo.ModifiedAt.AsTime(),
+			}
+			snapshot.Dirs++
+			return nil
+		}
+
+		snapshot.Files++
+		snapshot.TotalSize += fileInfo.Size
+
+		// parents are walked before their children, so every ancestor exists
+		for dir := parent; ; dir = path.Dir(dir) {
+			if dir == "." || dir == "/" {
+				dir = ""
+			}
+
+			dirs[dir].Size += fileInfo.Size
+			dirs[dir].Files++
+
+			if dir == "" {
+				break
+			}
+		}
+
+		extension := strings.ToLower(strings.TrimPrefix(path.Ext(fileInfo.Name), "."))
+		addBucket("type", extension, fileInfo.Size)
+
+		age := "older"
+		for _, bucket := range usageAgeBuckets {
+			if now.Sub(fileInfo.ModifiedAt.AsTime()) < bucket.age {
+				age = bucket.name
+				break
+			}
+		}
+		addBucket("age", age, fileInfo.Size)
+
+		files = append(files, &UsageEntry{
+			SnapshotId: snapshot.ID,
+			Parent:     parent,
+			Path:       relPath,
+			Name:       fileInfo.Name,
+			Size:       fileInfo.Size,
+			Files:      1,
+			ModifiedAt: fileInfo.ModifiedAt.AsTime(),
+		})
+
+		if len(files) > usageTopFiles*2 {
+			files = u.largest(files)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	entries := u.largest(files)
+	for _, dir := range dirs {
+		entries = append(entries, dir)
+	}
+
+	var bucketList []*UsageBucket
+	for _, bucket := range buckets {
+		bucketList = append(bucketList, bucket)
+	}
+
+	return database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(entries, 500).Error; err != nil {
+			return err
+		}
+
+		if len(bucketList) == 0 {
+			return nil
+		}
+
+		return tx.CreateInBatches(bucketList, 500).Error
+	})
+}
+
+func (u *UsageService) largest(files []*UsageEntry) []*UsageEntry {
+	sort.Slice(files, func(i, j int) bool { return files[i].Size > files[j].Size })
+	if len(files) > usageTopFiles {
+		files = files[:usageTopFiles]
+	}
+
+	return files
+}
+
+func (u *UsageService) cleanPath(relPath string) string {
+	return strings.Trim(path.Clean("/"+relPath), "/")
+}
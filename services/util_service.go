@@ -1,6 +1,11 @@
 package services
 
 import (
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
 	"github.com/pixelfs/pixelfs/util"
 )
 
@@ -17,3 +22,64 @@ func (u *UtilService) FormatBytes(bytes uint64) string {
 func (u *UtilService) ParseBytes(str string) (uint64, error) {
 	return util.ParseBytes(str)
 }
+
+var (
+	globCache   = make(map[string]*regexp.Regexp)
+	globCacheMu sync.Mutex
+)
+
+// matchGlob reports whether the slash separated relative path matches the
+// pattern. "*" and "?" do not match "/", "**" matches any number of
+// directories and a pattern without "/" is matched against the base name.
+func matchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSpace(pattern), "/")
+	if pattern == "" {
+		return false
+	}
+
+	if !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+
+	globCacheMu.Lock()
+	re, ok := globCache[pattern]
+	if !ok {
+		var expr strings.Builder
+		expr.WriteString("^")
+		runes := []rune(pattern)
+		for i := 0; i < len(runes); i++ {
+			rest := string(runes[i:])
+			switch c := runes[i]; {
+			case strings.HasPrefix(rest, "**/"):
+				expr.WriteString("(.*/)?")
+				i += 2
+			case strings.HasPrefix(rest, "**"):
+				expr.WriteString(".*")
+				i++
+			case c == '*':
+				expr.WriteString("[^/]*")
+			case c == '?':
+				expr.WriteString("[^/]")
+			default:
+				expr.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		}
+		expr.WriteString("$")
+
+		re = regexp.MustCompile(expr.String())
+		globCache[pattern] = re
+	}
+	globCacheMu.Unlock()
+
+	return re.MatchString(name)
+}
+
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
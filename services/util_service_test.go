@@ -0,0 +1,38 @@
+package services
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.txt", "b.txt", true},
+		{"*.txt", "a/b.txt", true},
+		{"*.txt", "b.txt.bak", false},
+		{"?.txt", "b.txt", true},
+		{"?.txt", "ab.txt", false},
+		{"a/*.txt", "a/b.txt", true},
+		{"a/*.txt", "a/c/b.txt", false},
+		{"**/b.txt", "b.txt", true},
+		{"**/b.txt", "a/c/b.txt", true},
+		{"a/**", "a/c/b.txt", true},
+		{"a/**", "b/c.txt", false},
+		{"/b.txt", "b.txt", true},
+		{"a+b.txt", "a+b.txt", true},
+		{"临时*", "临时文件.txt", true},
+		{"临时*", "文档/临时.doc", true},
+		{"临时*", "正式.doc", false},
+		{"?.txt", "é.txt", true},
+		{"é.txt", "é.txt", true},
+		{"照片/**/*.jpg", "照片/2024/夏天/海边.jpg", true},
+		{"", "b.txt", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
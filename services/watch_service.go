@@ -0,0 +1,612 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	pb "github.com/pixelfs/pixelfs/gen/pixelfs/v1"
+	"github.com/pixelfs/pixelfs/log"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	EventWatchUploaded = "watch:uploaded"
+
+	watchDefaultSettleSeconds = 5
+	watchMaxUploads           = 500
+)
+
+// WatchService uploads new or changed files of local folders as soon as they
+// have not been written to for the settle time of their folder.
+type WatchService struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	watcher *fsnotify.Watcher
+	folders []*WatchFolder
+	pending map[string]*watchPending
+	queue   chan *watchPending
+}
+
+type WatchFolder struct {
+	gorm.Model
+
+	Name          string
+	LocalPath     string
+	NodeId        string
+	Location      string
+	Path          string
+	Include       []string `gorm:"serializer:json"` // empty includes every file
+	Exclude       []string `gorm:"serializer:json"`
+	Recursive     bool
+	SettleSeconds int
+	AfterUpload   string // keep, delete, archive
+	ArchivePath   string
+	Enabled       bool
+}
+
+type WatchUpload struct {
+	gorm.Model
+
+	FolderId   uint `gorm:"index"`
+	LocalPath  string
+	RemotePath string
+	Size       int64
+	Status     string // success, failed
+	Error      string
+}
+
+// WatchFileState is the last uploaded version of a local file, so files that
+// changed while the app was closed are found on startup.
+type WatchFileState struct {
+	ID         uint   `gorm:"primarykey"`
+	FolderId   uint   `gorm:"uniqueIndex:idx_watch_file_state"`
+	LocalPath  string `gorm:"uniqueIndex:idx_watch_file_state"`
+	Size       int64
+	ModifiedAt time.Time
+	UploadedAt time.Time
+}
+
+type watchPending struct {
+	folder    *WatchFolder
+	localPath string
+	size      int64
+	modTime   time.Time
+	lastEvent time.Time
+}
+
+var watch *WatchService
+var onceWatch sync.Once
+
+func NewWatchService() *WatchService {
+	if watch == nil {
+		onceWatch.Do(func() {
+			watch = &WatchService{
+				pending: make(map[string]*watchPending),
+				queue:   make(chan *watchPending, 100),
+			}
+		})
+	}
+
+	return watch
+}
+
+func (w *WatchService) OnStartup(ctx context.Context, _ application.ServiceOptions) error {
+	w.ctx = ctx
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	w.watcher = watcher
+
+	go w.handleEvents(ctx)
+	go w.settle(ctx)
+	go w.upload(ctx)
+
+	go func() {
+		if err := w.reload(); err != nil {
+			log.Error().Err(err).Msg("failed to watch folders")
+		}
+	}()
+
+	return nil
+}
+
+func (w *WatchService) OnShutdown() error {
+	if w.watcher == nil {
+		return nil
+	}
+
+	return w.watcher.Close()
+}
+
+func (w *WatchService) GetWatchFolders() ([]*WatchFolder, error) {
+	var folders []*WatchFolder
+	if err := database.db.Order("id").Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+func (w *WatchService) CreateWatchFolder(folder *WatchFolder) (*WatchFolder, error) {
+	if err := w.validate(folder); err != nil {
+		return nil, err
+	}
+
+	folder.ID = 0
+	if err := database.db.Create(folder).Error; err != nil {
+		return nil, err
+	}
+
+	return folder, w.reload()
+}
+
+func (w *WatchService) UpdateWatchFolder(folder *WatchFolder) (*WatchFolder, error) {
+	if err := w.validate(folder); err != nil {
+		return nil, err
+	}
+
+	var current WatchFolder
+	if err := database.db.First(&current, folder.ID).Error; err != nil {
+		return nil, err
+	}
+
+	folder.CreatedAt = current.CreatedAt
+	if err := database.db.Save(folder).Error; err != nil {
+		return nil, err
+	}
+
+	return folder, w.reload()
+}
+
+func (w *WatchService) RemoveWatchFolder(id uint) error {
+	err := database.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("folder_id = ?", id).Delete(&WatchUpload{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("folder_id = ?", id).Delete(&WatchFileState{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&WatchFolder{}, id).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.reload()
+}
+
+func (w *WatchService) GetWatchUploads(folderId uint, limit int) ([]*WatchUpload, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var uploads []*WatchUpload
+	if err := database.db.Where("folder_id = ?", folderId).Order("id desc").Limit(limit).Find(&uploads).Error; err != nil {
+		return nil, err
+	}
+
+	return uploads, nil
+}
+
+func (w *WatchService) validate(folder *WatchFolder) error {
+	if folder.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if folder.NodeId == "" || folder.Location == "" {
+		return errors.New("remote directory is required")
+	}
+
+	stat, err := os.Stat(folder.LocalPath)
+	if err != nil {
+		return err
+	}
+
+	if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", folder.LocalPath)
+	}
+
+	if folder.SettleSeconds < 0 {
+		return errors.New("settle time must not be negative")
+	}
+
+	switch folder.AfterUpload {
+	case "", "keep", "delete":
+	case "archive":
+		if folder.ArchivePath == "" {
+			return errors.New("archive directory is required")
+		}
+
+		// archived files must not be picked up again
+		if rel, err := filepath.Rel(folder.LocalPath, folder.ArchivePath); err == nil && !strings.HasPrefix(rel, "..") {
+			return errors.New("archive directory must be outside of the watched folder")
+		}
+	default:
+		return fmt.Errorf("unknown action after upload: %s", folder.AfterUpload)
+	}
+
+	return nil
+}
+
+// reload watches the directories of the enabled folders again and queues
+// the files that were added or changed while they were not watched.
+func (w *WatchService) reload() error {
+	var folders []*WatchFolder
+	if err := database.db.Where("enabled = ?", true).Find(&folders).Error; err != nil {
+		return err
+	}
+
+	changed := make(map[*WatchFolder][]string, len(folders))
+	for _, folder := range folders {
+		files, err := w.scan(folder)
+		if err != nil {
+			log.Warn().Err(err).Msgf("failed to scan watch folder %s", folder.LocalPath)
+		}
+
+		changed[folder] = files
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, name := range w.watcher.WatchList() {
+		_ = w.watcher.Remove(name)
+	}
+
+	w.folders = folders
+	w.pending = make(map[string]*watchPending)
+
+	var errs error
+	for _, folder := range folders {
+		errs = errors.Join(errs, w.addDir(folder, folder.LocalPath))
+
+		for _, localPath := range changed[folder] {
+			w.enqueue(folder, localPath)
+		}
+	}
+
+	return errs
+}
+
+// scan returns the files of the folder that were not uploaded yet, or that
+// changed since their last upload.
+func (w *WatchService) scan(folder *WatchFolder) ([]string, error) {
+	var states []*WatchFileState
+	if err := database.db.Where("folder_id = ?", folder.ID).Find(&states).Error; err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]*WatchFileState, len(states))
+	for _, state := range states {
+		known[state.LocalPath] = state
+	}
+
+	var files []string
+	err := filepath.WalkDir(folder.LocalPath, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel := w.relPath(folder, p)
+		if entry.IsDir() {
+			if rel != "" && (!folder.Recursive || matchAnyGlob(folder.Exclude, rel)) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		state, ok := known[p]
+		if ok && state.Size == info.Size() && state.ModifiedAt.Equal(info.ModTime()) {
+			return nil
+		}
+
+		files = append(files, p)
+		return nil
+	})
+
+	return files, err
+}
+
+// addDir watches dir and, for recursive folders, its subdirectories.
+func (w *WatchService) addDir(folder *WatchFolder, dir string) error {
+	if !folder.Recursive {
+		return w.watcher.Add(dir)
+	}
+
+	return filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if rel := w.relPath(folder, p); rel != "" && matchAnyGlob(folder.Exclude, rel) {
+			return filepath.SkipDir
+		}
+
+		return w.watcher.Add(p)
+	})
+}
+
+func (w *WatchService) handleEvents(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			log.Warn().Err(err).Msg("watch folder error")
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			w.handleEvent(event)
+		}
+	}
+}
+
+func (w *WatchService) handleEvent(event fsnotify.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	folder := w.folderOf(event.Name)
+	if folder == nil {
+		return
+	}
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		delete(w.pending, event.Name)
+		return
+	}
+
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		return
+	}
+
+	info, err := os.Stat(event.Name)
+	if err != nil {
+		return
+	}
+
+	if info.IsDir() {
+		if !event.Has(fsnotify.Create) || !folder.Recursive {
+			return
+		}
+
+		if err := w.addDir(folder, event.Name); err != nil {
+			log.Warn().Err(err).Msgf("failed to watch %s", event.Name)
+		}
+
+		// files that were moved in together with the directory
+		_ = filepath.WalkDir(event.Name, func(p string, entry fs.DirEntry, err error) error {
+			if err == nil && entry.Type().IsRegular() {
+				w.enqueue(folder, p)
+			}
+
+			return nil
+		})
+		return
+	}
+
+	if info.Mode().IsRegular() {
+		w.enqueue(folder, event.Name)
+	}
+}
+
+func (w *WatchService) enqueue(folder *WatchFolder, localPath string) {
+	rel := w.relPath(folder, localPath)
+	if rel == "" || matchAnyGlob(folder.Exclude, rel) || (len(folder.Include) > 0 && !matchAnyGlob(folder.Include, rel)) {
+		return
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return
+	}
+
+	pending, ok := w.pending[localPath]
+	if !ok {
+		pending = &watchPending{folder: folder, localPath: localPath}
+		w.pending[localPath] = pending
+	}
+
+	pending.size = info.Size()
+	pending.modTime = info.ModTime()
+	pending.lastEvent = time.Now()
+}
+
+// settle hands files to the uploader once their size and mtime stopped
+// changing for the settle time of the folder.
+func (w *WatchService) settle(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var ready []*watchPending
+
+		w.mu.Lock()
+		for localPath, pending := range w.pending {
+			settle := pending.folder.SettleSeconds
+			if settle == 0 {
+				settle = watchDefaultSettleSeconds
+			}
+
+			if time.Since(pending.lastEvent) < time.Duration(settle)*time.Second {
+				continue
+			}
+
+			info, err := os.Stat(localPath)
+			if err != nil {
+				delete(w.pending, localPath)
+				continue
+			}
+
+			if info.Size() != pending.size || !info.ModTime().Equal(pending.modTime) {
+				pending.size = info.Size()
+				pending.modTime = info.ModTime()
+				pending.lastEvent = time.Now()
+				continue
+			}
+
+			delete(w.pending, localPath)
+			ready = append(ready, pending)
+		}
+		w.mu.Unlock()
+
+		for _, pending := range ready {
+			select {
+			case <-ctx.Done():
+				return
+			case w.queue <- pending:
+			}
+		}
+	}
+}
+
+func (w *WatchService) upload(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case pending := <-w.queue:
+			w.uploadFile(pending)
+		}
+	}
+}
+
+func (w *WatchService) uploadFile(pending *watchPending) {
+	folder := pending.folder
+	rel := w.relPath(folder, pending.localPath)
+	dest := &pb.FileContext{NodeId: folder.NodeId, Location: folder.Location, Path: path.Join(folder.Path, rel)}
+
+	model := WatchUpload{
+		FolderId:   folder.ID,
+		LocalPath:  pending.localPath,
+		RemotePath: dest.Path,
+		Size:       pending.size,
+		Status:     "success",
+	}
+
+	err := file.mkdirAll(&pb.FileContext{NodeId: dest.NodeId, Location: dest.Location, Path: path.Dir(dest.Path)})
+	if err == nil {
+		err = file.uploadLocalFile(dest, pending.localPath)
+	}
+
+	if err == nil {
+		err = w.afterUpload(folder, pending.localPath, rel)
+	}
+
+	if err != nil {
+		model.Status = "failed"
+		model.Error = err.Error()
+		log.Error().Err(err).Msgf("failed to upload watched file %s", pending.localPath)
+	} else {
+		err = database.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "folder_id"}, {Name: "local_path"}},
+			DoUpdates: clause.AssignmentColumns([]string{"size", "modified_at", "uploaded_at"}),
+		}).Create(&WatchFileState{
+			FolderId:   folder.ID,
+			LocalPath:  pending.localPath,
+			Size:       pending.size,
+			ModifiedAt: pending.modTime,
+			UploadedAt: time.Now(),
+		}).Error
+		if err != nil {
+			log.Error().Err(err).Msg("failed to record watched file state")
+		}
+	}
+
+	if err := database.db.Create(&model).Error; err != nil {
+		log.Error().Err(err).Msg("failed to record watched upload")
+	}
+
+	var ids []uint
+	database.db.Model(&WatchUpload{}).Where("folder_id = ?", folder.ID).
+		Order("id desc").Offset(watchMaxUploads).Pluck("id", &ids)
+	if len(ids) > 0 {
+		database.db.Unscoped().Delete(&WatchUpload{}, ids)
+	}
+
+	application.Get().EmitEvent(EventWatchUploaded, &model)
+}
+
+func (w *WatchService) afterUpload(folder *WatchFolder, localPath, rel string) error {
+	switch folder.AfterUpload {
+	case "delete":
+		return os.Remove(localPath)
+	case "archive":
+		archivePath := filepath.Join(folder.ArchivePath, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+			return err
+		}
+
+		return os.Rename(localPath, archivePath)
+	}
+
+	return nil
+}
+
+// folderOf returns the folder that watches the local path.
+func (w *WatchService) folderOf(localPath string) *WatchFolder {
+	for _, folder := range w.folders {
+		rel := w.relPath(folder, localPath)
+		if rel == "" {
+			continue
+		}
+
+		if folder.Recursive || !strings.Contains(rel, "/") {
+			return folder
+		}
+	}
+
+	return nil
+}
+
+// relPath returns the slash separated path below the folder, or "" if the
+// local path is not inside it.
+func (w *WatchService) relPath(folder *WatchFolder, localPath string) string {
+	rel, err := filepath.Rel(folder.LocalPath, localPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+
+	return filepath.ToSlash(rel)
+}